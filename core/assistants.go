@@ -0,0 +1,251 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	mtproto "github.com/amarnathcjd/gogram"
+	tg "github.com/amarnathcjd/gogram/telegram"
+	ntg "shizumusic/ntgcalls"
+)
+
+// assistantCooldown is how long a limited or disconnected assistant gets no new chats
+const assistantCooldown = 10 * time.Minute
+
+// errNoAssistant is returned when every assistant is unavailable for a chat
+var errNoAssistant = errors.New("no assistant is available to join the voice chat")
+
+// Assistant is one assistant account streaming with its own ntgcalls client
+type Assistant struct {
+	Index  int // 1 for STRING_SESSION, 2 for STRING_SESSION2 and so on
+	Client *tg.Client
+
+	ntg           *ntg.Client
+	streamSenders map[int]*mtproto.MTProto // guarded by Calls.broadcastsMu
+
+	mu          sync.Mutex
+	failure     string    // why the assistant gets no chats, "" while healthy
+	failedUntil time.Time // zero for failures only a restart fixes
+}
+
+// AssistantStore remembers which assistant each chat was served by
+type AssistantStore interface {
+	GetChatAssistant(chatID int64) int64
+	SetChatAssistant(chatID, assistantID int64) error
+}
+
+// AssistantStatus describes an assistant for status displays
+type AssistantStatus struct {
+	Index     int
+	ID        int64
+	Username  string
+	Name      string
+	Chats     int
+	Connected bool
+	Failure   string // "" while healthy
+}
+
+func newAssistant(index int, client *tg.Client) *Assistant {
+	return &Assistant{
+		Index:         index,
+		Client:        client,
+		ntg:           ntg.NTgCalls(),
+		streamSenders: make(map[int]*mtproto.MTProto),
+	}
+}
+
+// ID returns the user ID of the assistant account
+func (a *Assistant) ID() int64 {
+	return a.Client.Me().ID
+}
+
+// Failure returns why the assistant gets no new chats, "" when it does
+func (a *Assistant) Failure() string {
+	if !a.Client.IsConnected() {
+		return "disconnected"
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.failure != "" && !a.failedUntil.IsZero() && time.Now().After(a.failedUntil) {
+		a.failure = ""
+		a.failedUntil = time.Time{}
+	}
+	return a.failure
+}
+
+// fail keeps the assistant out of new assignments, for cooldown or until a restart when 0
+func (a *Assistant) fail(reason string, cooldown time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failure = reason
+	a.failedUntil = time.Time{}
+	if cooldown > 0 {
+		a.failedUntil = time.Now().Add(cooldown)
+	}
+	log.Printf(">> Assistant %d is unavailable: %s", a.Index, reason)
+}
+
+// SetAssistantStore sets where the assistant of each chat is remembered
+// Chats go back to the same assistant as long as it is available
+func (c *Calls) SetAssistantStore(store AssistantStore) {
+	c.assignmentsMu.Lock()
+	defer c.assignmentsMu.Unlock()
+	c.assistantStore = store
+}
+
+// Assistants returns the status of every assistant
+func (c *Calls) Assistants() []AssistantStatus {
+	c.assignmentsMu.RLock()
+	chats := make(map[*Assistant]int)
+	for _, a := range c.chatAssistants {
+		chats[a]++
+	}
+	c.assignmentsMu.RUnlock()
+
+	statuses := make([]AssistantStatus, 0, len(c.assistants))
+	for _, a := range c.assistants {
+		me := a.Client.Me()
+		statuses = append(statuses, AssistantStatus{
+			Index:     a.Index,
+			ID:        me.ID,
+			Username:  me.Username,
+			Name:      strings.TrimSpace(me.FirstName + " " + me.LastName),
+			Chats:     chats[a],
+			Connected: a.Client.IsConnected(),
+			Failure:   a.Failure(),
+		})
+	}
+	return statuses
+}
+
+// AssistantClient returns the client of the assistant serving a chat
+func (c *Calls) AssistantClient(chatID int64) *tg.Client {
+	return c.assistant(chatID).Client
+}
+
+// assistant returns who serves a chat: the assistant it is joined with,
+// else the one it was served by before, else the primary assistant
+func (c *Calls) assistant(chatID int64) *Assistant {
+	c.assignmentsMu.RLock()
+	a, ok := c.chatAssistants[chatID]
+	c.assignmentsMu.RUnlock()
+	if ok {
+		return a
+	}
+	if a := c.stickyAssistant(chatID); a != nil {
+		return a
+	}
+	return c.primary()
+}
+
+// primary returns the STRING_SESSION assistant, private calls always use it
+func (c *Calls) primary() *Assistant {
+	return c.assistants[0]
+}
+
+// pickAssistant chooses who joins a chat, its previous assistant when available,
+// else the available assistant serving the fewest chats
+func (c *Calls) pickAssistant(chatID int64, skip map[*Assistant]bool) *Assistant {
+	if a := c.stickyAssistant(chatID); a != nil && !skip[a] && a.Failure() == "" {
+		return a
+	}
+
+	c.assignmentsMu.RLock()
+	load := make(map[*Assistant]int)
+	for _, a := range c.chatAssistants {
+		load[a]++
+	}
+	c.assignmentsMu.RUnlock()
+
+	var best *Assistant
+	for _, a := range c.assistants {
+		if skip[a] || a.Failure() != "" {
+			continue
+		}
+		if best == nil || load[a] < load[best] {
+			best = a
+		}
+	}
+	return best
+}
+
+func (c *Calls) stickyAssistant(chatID int64) *Assistant {
+	c.assignmentsMu.RLock()
+	store := c.assistantStore
+	c.assignmentsMu.RUnlock()
+	if store == nil || len(c.assistants) == 1 {
+		return nil
+	}
+
+	id := store.GetChatAssistant(chatID)
+	if id == 0 {
+		return nil
+	}
+	for _, a := range c.assistants {
+		if a.ID() == id {
+			return a
+		}
+	}
+	return nil
+}
+
+// assign binds a chat to the assistant joining it and remembers the choice
+func (c *Calls) assign(chatID int64, a *Assistant) {
+	c.assignmentsMu.Lock()
+	c.chatAssistants[chatID] = a
+	store := c.assistantStore
+	c.assignmentsMu.Unlock()
+
+	if store != nil && len(c.assistants) > 1 && store.GetChatAssistant(chatID) != a.ID() {
+		if err := store.SetChatAssistant(chatID, a.ID()); err != nil {
+			log.Printf(">> Saving assistant of chat %d failed: %v", chatID, err)
+		}
+	}
+}
+
+func (c *Calls) unassign(chatID int64) {
+	c.assignmentsMu.Lock()
+	defer c.assignmentsMu.Unlock()
+	delete(c.chatAssistants, chatID)
+}
+
+// failover marks an assistant that failed to join a chat
+// It reports whether another assistant might succeed instead
+func (c *Calls) failover(a *Assistant, err error) bool {
+	var membershipErr *membershipError
+	msg := err.Error()
+	switch {
+	case containsAny(msg, "AUTH_KEY_UNREGISTERED", "AUTH_KEY_DUPLICATED", "SESSION_REVOKED", "USER_DEACTIVATED"):
+		a.fail("session revoked or account deleted", 0)
+		return true
+	case containsAny(msg, "FLOOD_WAIT", "FLOOD_PREMIUM_WAIT", "PEER_FLOOD"):
+		a.fail("limited by Telegram", assistantCooldown)
+		return true
+	case !a.Client.IsConnected():
+		a.fail("disconnected", assistantCooldown)
+		return true
+	case errors.As(err, &membershipErr),
+		containsAny(msg, "USER_BANNED_IN_CHANNEL", "CHANNEL_PRIVATE", "failed to resolve peer"):
+		// Only this chat is off limits for the assistant
+		return true
+	}
+	return false
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeAssistant names an assistant for logs and errors
+func describeAssistant(a *Assistant) string {
+	return fmt.Sprintf("assistant %d (%d)", a.Index, a.ID())
+}
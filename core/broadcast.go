@@ -0,0 +1,185 @@
+package core
+
+import (
+	"fmt"
+	"log"
+
+	mtproto "github.com/amarnathcjd/gogram"
+	tg "github.com/amarnathcjd/gogram/telegram"
+	ntg "shizumusic/ntgcalls"
+)
+
+// broadcastCall is a joined call that ntgcalls receives as broadcast segments
+// Telegram switches large channel streams and RTMP streams to this mode
+type broadcastCall struct {
+	assistant *Assistant
+	call      *tg.InputGroupCallObj
+	mode      ntg.ConnectionMode
+	streamDC  int
+}
+
+// tlRequest is any Telegram API request, the tl package of gogram is internal
+type tlRequest interface {
+	CRC() uint32
+}
+
+// setupBroadcast reads the connection mode of a freshly connected call
+// Broadcast calls get their segments served from the stream DC of the call
+func (c *Calls) setupBroadcast(a *Assistant, chatID int64, groupCall *tg.InputGroupCallObj) {
+	mode, err := a.ntg.GetConnectionMode(chatID)
+	if err != nil {
+		log.Printf(">> Reading connection mode failed for chat %d: %v", chatID, err)
+		return
+	}
+	if mode == ntg.RtcConnection {
+		return
+	}
+
+	streamDC := a.Client.GetDC()
+	if result, err := a.Client.PhoneGetGroupCall(groupCall, 1); err == nil {
+		if call, ok := result.Call.(*tg.GroupCallObj); ok && call.StreamDcID != 0 {
+			streamDC = int(call.StreamDcID)
+		}
+	}
+
+	c.broadcastsMu.Lock()
+	c.broadcasts[chatID] = &broadcastCall{assistant: a, call: groupCall, mode: mode, streamDC: streamDC}
+	c.broadcastsMu.Unlock()
+
+	log.Printf(">> Chat %d joined in %s mode, segments come from DC%d", chatID, connectionModeName(mode), streamDC)
+}
+
+// ConnectionMode returns how ntgcalls exchanges media with the call of a chat
+func (c *Calls) ConnectionMode(chatID int64) ntg.ConnectionMode {
+	c.broadcastsMu.Lock()
+	defer c.broadcastsMu.Unlock()
+	if b, ok := c.broadcasts[chatID]; ok {
+		return b.mode
+	}
+	return ntg.RtcConnection
+}
+
+func (c *Calls) broadcast(chatID int64) *broadcastCall {
+	c.broadcastsMu.Lock()
+	defer c.broadcastsMu.Unlock()
+	return c.broadcasts[chatID]
+}
+
+func (c *Calls) forgetBroadcast(chatID int64) {
+	c.broadcastsMu.Lock()
+	defer c.broadcastsMu.Unlock()
+	delete(c.broadcasts, chatID)
+}
+
+// sendBroadcastTimestamp answers ntgcalls with the newest segment time of a broadcast call
+func (c *Calls) sendBroadcastTimestamp(chatID int64) {
+	b := c.broadcast(chatID)
+	if b == nil {
+		return
+	}
+
+	result, err := c.invokeStreamDC(b, &tg.PhoneGetGroupCallStreamChannelsParams{Call: b.call})
+	if err != nil {
+		log.Printf(">> Fetching stream channels failed for chat %d: %v", chatID, err)
+		return
+	}
+	channels, ok := result.(*tg.PhoneGroupCallStreamChannels)
+	if !ok || len(channels.Channels) == 0 {
+		log.Printf(">> No stream channels for chat %d", chatID)
+		return
+	}
+
+	var timestamp int64
+	for _, channel := range channels.Channels {
+		if channel.LastTimestampMs > timestamp {
+			timestamp = channel.LastTimestampMs
+		}
+	}
+	if err := b.assistant.ntg.SendBroadcastTimestamp(chatID, timestamp); err != nil {
+		log.Printf(">> Sending broadcast timestamp failed for chat %d: %v", chatID, err)
+	}
+}
+
+// sendBroadcastPart downloads a segment part of a broadcast call and hands it to ntgcalls
+// Parts that are not out yet or already gone are reported so ntgcalls retries or resyncs
+func (c *Calls) sendBroadcastPart(chatID int64, request ntg.SegmentPartRequest) {
+	b := c.broadcast(chatID)
+	if b == nil {
+		return
+	}
+
+	location := &tg.InputGroupCallStream{
+		Call:   b.call,
+		TimeMs: request.Timestamp,
+	}
+	if request.Quality != ntg.SegmentQualityNone {
+		location.VideoChannel = request.ChannelID
+		location.VideoQuality = int32(request.Quality)
+	}
+
+	status := ntg.SegmentStatusSuccess
+	var data []byte
+
+	result, err := c.invokeStreamDC(b, &tg.UploadGetFileParams{
+		Location: location,
+		Limit:    request.Limit,
+	})
+	switch {
+	case err == nil:
+		file, ok := result.(*tg.UploadFileObj)
+		if !ok {
+			status = ntg.SegmentStatusNotReady
+			break
+		}
+		data = file.Bytes
+	case tg.MatchError(err, "TIME_TOO_SMALL"), tg.MatchError(err, "TIME_INVALID"):
+		status = ntg.SegmentStatusResyncNeeded
+	default:
+		// TIME_TOO_BIG and friends, the segment is not out yet
+		status = ntg.SegmentStatusNotReady
+	}
+
+	if err := b.assistant.ntg.SendBroadcastPart(chatID, request.SegmentID, request.PartID, status, request.QualityUpdate, data); err != nil {
+		log.Printf(">> Sending broadcast part failed for chat %d: %v", chatID, err)
+	}
+}
+
+// invokeStreamDC sends a request to the DC the segments of a broadcast call live on
+func (c *Calls) invokeStreamDC(b *broadcastCall, request tlRequest) (any, error) {
+	sender, err := c.streamSender(b.assistant, b.streamDC)
+	if err != nil {
+		return nil, err
+	}
+	return sender.MakeRequest(request)
+}
+
+// streamSender returns a connection of an assistant to a DC, reused across segments
+func (c *Calls) streamSender(a *Assistant, dc int) (*mtproto.MTProto, error) {
+	if dc == a.Client.GetDC() {
+		return a.Client.MTProto, nil
+	}
+
+	c.broadcastsMu.Lock()
+	defer c.broadcastsMu.Unlock()
+	if sender, ok := a.streamSenders[dc]; ok {
+		return sender, nil
+	}
+
+	sender, err := a.Client.CreateExportedSender(dc, false)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to stream DC%d failed: %w", dc, err)
+	}
+	a.streamSenders[dc] = sender
+	return sender, nil
+}
+
+func connectionModeName(mode ntg.ConnectionMode) string {
+	switch mode {
+	case ntg.StreamConnection:
+		return "stream"
+	case ntg.RTMPConnection:
+		return "RTMP"
+	default:
+		return "RTC"
+	}
+}
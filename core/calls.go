@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -11,82 +12,303 @@ import (
 )
 
 type Calls struct {
-	client *tg.Client
-	ntg    *ntg.Client
+	assistants     []*Assistant
+	chatAssistants map[int64]*Assistant
+	assistantStore AssistantStore
+	inviter        *tg.Client
+	assignmentsMu  sync.RWMutex
+
+	players *PlayerStates
 
 	activeSessions   map[int64]*VCSession
 	activeSessionsMu sync.RWMutex
+
+	effectsStore   EffectsStore
+	effectsStoreMu sync.RWMutex
+
+	streamEndHandlers       []StreamEndHandler
+	presentationEndHandlers []PresentationEndHandler
+	streamEndHandlersMu     sync.RWMutex
+
+	listeners   map[int64]int
+	listenersMu sync.RWMutex
+
+	qualitySource   QualitySource
+	qualitySourceMu sync.RWMutex
+
+	vcSettingsSource VCSettingsSource
+	vcSettingsMu     sync.RWMutex
+
+	privateCalls           map[int64]*P2PConfig
+	incomingCall           IncomingCallHandler
+	privateCallEndHandlers []PrivateCallEndHandler
+	privateCallsMu         sync.Mutex
+
+	broadcasts   map[int64]*broadcastCall
+	broadcastsMu sync.Mutex
+
+	recordings        map[int64]*Recording
+	recordingHandlers []RecordingHandler
+	recordingsMu      sync.Mutex
+
+	connectionHistory       map[int64][]ConnectionEvent
+	reconnecting            map[int64]bool
+	reconnectFailedHandlers []ReconnectFailedHandler
+	connectionsMu           sync.Mutex
 }
 
+// QualitySource returns the stream quality a chat picked
+type QualitySource func(chatID int64) StreamQuality
+
+// EffectsStore keeps the audio effects of each chat
+type EffectsStore interface {
+	GetAudioEffects(chatID int64) AudioEffects
+	SetAudioEffects(chatID int64, bassBoost int, speed float64) error
+}
+
+// StreamEndHandler is called once the audio stream of a chat has finished
+type StreamEndHandler func(chatID int64)
+
 type VCSession struct {
 	ChatID    int64
 	FilePath  string
 	IsVideo   bool
 	StartTime time.Time
+	Offset    int // track position in seconds the stream started at
+	Muted     bool
+
+	// Wall-clock pauses, whether the chat is paused is up to its PlayerState
+	pausedAt  time.Time
+	pausedFor time.Duration
+	clockBase int // stream clock seconds already folded into Offset
+	groupCall *tg.InputGroupCallObj
+	started   bool // the assistant started the group call itself
+
+	// Video shared as presentation next to the camera, "" when none
+	Presentation      string
+	presentationStart time.Time
 }
 
-type P2PConfig struct {
-	DhConfig       ntg.DhConfig
-	GAorB          []byte
-	KeyFingerprint int64
-	IsOutgoing     bool
-	PhoneCall      *tg.PhoneCallObj
-	WaitData       chan error
+// elapsed returns the wall-clock time the current sources played, pauses excluded
+func (s *VCSession) elapsed(paused bool) time.Duration {
+	now := time.Now()
+	played := now.Sub(s.StartTime) - s.pausedFor
+	if paused {
+		played -= now.Sub(s.pausedAt)
+	}
+	if played < 0 {
+		played = 0
+	}
+	return played
+}
+
+// position estimates the current track position in seconds from the wall clock
+func (s *VCSession) position(speed float64, paused bool) int {
+	return s.Offset + int(s.elapsed(paused).Seconds()*speed)
+}
+
+// streamPosition returns the current track position from the ntgcalls stream clock
+// A clock running ahead of the wall clock still belongs to replaced sources,
+// the wall-clock estimate is used until it restarts
+func (s *VCSession) streamPosition(clock int, speed float64, paused bool) int {
+	played := clock - s.clockBase
+	if played < 0 || float64(played) > s.elapsed(paused).Seconds()+1 {
+		return s.position(speed, paused)
+	}
+	return s.Offset + int(float64(played)*speed)
 }
 
-func NewCalls(client *tg.Client) *Calls {
-	return &Calls{
-		client:         client,
-		ntg:            ntg.NTgCalls(),
+// NewCalls streams through every given assistant, the first one is the primary
+func NewCalls(clients ...*tg.Client) *Calls {
+	c := &Calls{
+		chatAssistants: make(map[int64]*Assistant),
+		players:        newPlayerStates(),
 		activeSessions: make(map[int64]*VCSession),
+		listeners:      make(map[int64]int),
+		privateCalls:   make(map[int64]*P2PConfig),
+		broadcasts:     make(map[int64]*broadcastCall),
+		recordings:     make(map[int64]*Recording),
+
+		connectionHistory: make(map[int64][]ConnectionEvent),
+		reconnecting:      make(map[int64]bool),
+	}
+	for i, client := range clients {
+		a := newAssistant(i+1, client)
+		c.assistants = append(c.assistants, a)
+		c.registerCallbacks(a)
 	}
+
+	return c
 }
 
-func (c *Calls) Start() error {
-	log.Println(">> Booting NTgCalls client...")
+// registerCallbacks routes the ntgcalls events of an assistant
+func (c *Calls) registerCallbacks(a *Assistant) {
+	// Broadcast calls pull their segments through the bot instead of RTC
+	a.ntg.OnRequestBroadcastTimestamp(func(chatId int64) {
+		go c.sendBroadcastTimestamp(chatId)
+	})
+	a.ntg.OnRequestBroadcastPart(func(chatId int64, request ntg.SegmentPartRequest) {
+		go c.sendBroadcastPart(chatId, request)
+	})
 
-	c.ntg.OnStreamEnd(func(chatId int64, streamType ntg.StreamType, device ntg.StreamDevice) {
+	a.ntg.OnFrame(c.handleFrames)
+	a.ntg.OnConnectionChange(c.handleConnectionChange)
+
+	a.ntg.OnStreamEnd(func(chatId int64, streamType ntg.StreamType, device ntg.StreamDevice) {
+		// Private calls hang up once their track is over
+		if c.IsPrivateCall(chatId) {
+			if streamType == ntg.AudioStream {
+				log.Printf(">> Private call track ended for user %d", chatId)
+				go c.HangUp(chatId)
+			}
+			return
+		}
+		// A finished presentation only ends the presentation
+		if device == ntg.ScreenStream {
+			log.Printf(">> Presentation ended for chat %d", chatId)
+			go c.StopPresentation(chatId)
+			return
+		}
+		// Video tracks end alongside their audio, only react once per track
+		if streamType != ntg.AudioStream {
+			return
+		}
 		log.Printf(">> Stream ended for chat %d", chatId)
-		c.LeaveVC(chatId)
+		c.handleStreamEnd(chatId)
 	})
+}
 
-	c.ntg.OnConnectionChange(func(chatId int64, state ntg.NetworkInfo) {
-		log.Printf(">> Connection changed for chat %d", chatId)
-	})
+// OnStreamEnd registers a handler that takes over stream-end events.
+// Without any handler the assistant simply leaves the voice chat.
+func (c *Calls) OnStreamEnd(handler StreamEndHandler) {
+	c.streamEndHandlersMu.Lock()
+	defer c.streamEndHandlersMu.Unlock()
+	c.streamEndHandlers = append(c.streamEndHandlers, handler)
+}
+
+func (c *Calls) handleStreamEnd(chatID int64) {
+	c.streamEndHandlersMu.RLock()
+	handlers := append([]StreamEndHandler{}, c.streamEndHandlers...)
+	c.streamEndHandlersMu.RUnlock()
+
+	if len(handlers) == 0 {
+		c.LeaveVC(chatID)
+		return
+	}
+	for _, handler := range handlers {
+		handler(chatID)
+	}
+}
+
+func (c *Calls) Start() error {
+	log.Println(">> Booting NTgCalls client...")
 
 	log.Println(">> NTgCalls client booted!")
 	return nil
 }
 
-func (c *Calls) getSelfPeer() (tg.InputPeer, error) {
-	me, err := c.client.GetMe()
+func (c *Calls) getSelfPeer(a *Assistant) (tg.InputPeer, error) {
+	me, err := a.Client.GetMe()
 	if err != nil {
 		return nil, err
 	}
-	return c.client.ResolvePeer(me.ID)
+	return a.Client.ResolvePeer(me.ID)
 }
 
 func (c *Calls) JoinVC(chatID int64, filePath string, video bool) error {
-	groupCall, err := c.GetInputGroupCall(chatID)
+	return c.JoinVCAt(chatID, filePath, video, 0)
+}
+
+// JoinVCAt joins the voice chat of a chat and starts streaming at offset seconds
+// Only an idle chat can be joined, the player is playing once this returns nil
+func (c *Calls) JoinVCAt(chatID int64, filePath string, video bool, offset int) error {
+	vcType := "voice"
+	if video {
+		vcType = "video"
+	}
+	if _, err := c.players.fire(chatID, EventJoin, vcType); err != nil {
+		return err
+	}
+
+	if err := c.joinAny(chatID, filePath, video, offset); err != nil {
+		c.players.fire(chatID, EventJoinFailed, "")
+		return err
+	}
+	c.players.fire(chatID, EventJoined, "")
+	return nil
+}
+
+// joinAny joins with the previous assistant of the chat or the least busy one,
+// another assistant takes over when the chosen one is banned, limited or offline
+// Failover only happens while joining, a dropped call gets it through its rejoin,
+// a running call is never moved to another assistant
+func (c *Calls) joinAny(chatID int64, filePath string, video bool, offset int) error {
+	tried := make(map[*Assistant]bool)
+	var lastErr error
+	for {
+		a := c.pickAssistant(chatID, tried)
+		if a == nil {
+			if lastErr != nil {
+				return lastErr
+			}
+			return errNoAssistant
+		}
+		tried[a] = true
+
+		c.assign(chatID, a)
+		err := c.joinVC(a, chatID, filePath, video, offset)
+		if err == nil {
+			return nil
+		}
+		c.unassign(chatID)
+		if !c.failover(a, err) {
+			return err
+		}
+		log.Printf(">> %s could not join chat %d, trying another: %v", describeAssistant(a), chatID, err)
+		lastErr = err
+	}
+}
+
+// joinVC joins a voice chat with one assistant
+// Chats that opted in get their group call started when none is running
+func (c *Calls) joinVC(a *Assistant, chatID int64, filePath string, video bool, offset int) (err error) {
+	if err := c.ensureMember(a, chatID); err != nil {
+		return err
+	}
+
+	settings := c.VCSettings(chatID)
+	groupCall, err := c.inputGroupCall(a, chatID)
+	started := false
+	if errors.Is(err, errNoGroupCall) && settings.AutoStart {
+		groupCall, err = c.startGroupCall(a, chatID, settings.Title)
+		started = err == nil
+	}
 	if err != nil {
 		return err
 	}
+	// A call nobody gets to hear is ended right away
+	defer func() {
+		if err != nil && started {
+			a.Client.PhoneDiscardGroupCall(groupCall)
+		}
+	}()
 
 	// 1️⃣ Create WebRTC offer
-	offer, err := c.ntg.CreateCall(chatID)
+	offer, err := a.ntg.CreateCall(chatID)
 	if err != nil {
 		return fmt.Errorf("CreateCall failed: %w", err)
 	}
 
-	joinAs, err := c.getSelfPeer()
+	joinAs, err := c.joinAsPeer(a, settings.JoinAs)
 	if err != nil {
-		return fmt.Errorf("getSelfPeer failed: %w", err)
+		a.ntg.Stop(chatID)
+		return err
 	}
 
-	log.Printf(">> Joining VC - chatID: %d, file: %s", chatID, filePath)
+	log.Printf(">> Joining VC - chatID: %d, file: %s, offset: %ds, assistant: %d", chatID, filePath, offset, a.Index)
 
 	// 2️⃣ Join Telegram group call
-	result, err := c.client.PhoneJoinGroupCall(&tg.PhoneJoinGroupCallParams{
+	result, err := a.Client.PhoneJoinGroupCall(&tg.PhoneJoinGroupCallParams{
 		Call:         groupCall,
 		JoinAs:       joinAs,
 		Muted:        false,
@@ -94,21 +316,21 @@ func (c *Calls) JoinVC(chatID int64, filePath string, video bool) error {
 		Params:       &tg.DataJson{Data: offer},
 	})
 	if err != nil {
-		c.ntg.Stop(chatID)
+		a.ntg.Stop(chatID)
 		return fmt.Errorf("PhoneJoinGroupCall failed: %w", err)
 	}
-
-	// 3️⃣ Extract transport params
-	var answer string
-	if updates, ok := result.(*tg.UpdatesObj); ok {
-		for _, upd := range updates.Updates {
-			if conn, ok := upd.(*tg.UpdateGroupCallConnection); ok {
-				answer = conn.Params.Data
-				break
-			}
+	// Telegram has the assistant in the call now, failing from here on
+	// hangs up and leaves so the next assistant finds the call clean
+	defer func() {
+		if err != nil {
+			a.ntg.Stop(chatID)
+			a.Client.PhoneLeaveGroupCall(tg.InputGroupCall(groupCall), 0)
+			c.forgetBroadcast(chatID)
 		}
-	}
+	}()
 
+	// 3️⃣ Extract transport params
+	answer := transportAnswer(result, false)
 	if answer == "" {
 		return fmt.Errorf("transport params missing from Telegram response")
 	}
@@ -116,32 +338,13 @@ func (c *Calls) JoinVC(chatID int64, filePath string, video bool) error {
 	log.Printf(">> Got transport answer from Telegram")
 
 	// 4️⃣ Connect NTgCalls with transport answer
-	if err := c.ntg.Connect(chatID, answer, false); err != nil {
+	if err := a.ntg.Connect(chatID, answer, false); err != nil {
 		return fmt.Errorf("Connect failed: %w", err)
 	}
+	c.setupBroadcast(a, chatID, groupCall)
 
-	// 5️⃣ Set stream sources - REAL MediaDescription structure
-	// Microphone = audio input, Camera = video input
-	media := ntg.MediaDescription{
-		Microphone: &ntg.AudioDescription{
-			MediaSource:  ntg.MediaSourceFFmpeg,
-			Input:        filePath,
-			SampleRate:   48000,
-			ChannelCount: 2,
-		},
-	}
-
-	if video {
-		media.Camera = &ntg.VideoDescription{
-			MediaSource: ntg.MediaSourceFFmpeg,
-			Input:       filePath,
-			Width:       1280,
-			Height:      720,
-			Fps:         24,
-		}
-	}
-
-	if err := c.ntg.SetStreamSources(chatID, ntg.CaptureStream, media); err != nil {
+	// 5️⃣ Set stream sources
+	if err := a.ntg.SetStreamSources(chatID, ntg.CaptureStream, mediaDescription(filePath, video, offset, c.Effects(chatID), c.Quality(chatID))); err != nil {
 		return fmt.Errorf("SetStreamSources failed: %w", err)
 	}
 
@@ -152,6 +355,9 @@ func (c *Calls) JoinVC(chatID int64, filePath string, video bool) error {
 		FilePath:  filePath,
 		IsVideo:   video,
 		StartTime: time.Now(),
+		Offset:    offset,
+		groupCall: groupCall,
+		started:   started,
 	}
 	c.activeSessionsMu.Unlock()
 
@@ -159,51 +365,362 @@ func (c *Calls) JoinVC(chatID int64, filePath string, video bool) error {
 	return nil
 }
 
+// ChangeStream swaps the playing file on an already joined call
+func (c *Calls) ChangeStream(chatID int64, filePath string, video bool) error {
+	return c.SeekStream(chatID, filePath, video, 0)
+}
+
+// SeekStream restarts the stream sources of a joined call at offset seconds
+func (c *Calls) SeekStream(chatID int64, filePath string, video bool, offset int) error {
+	if !c.IsActive(chatID) {
+		return fmt.Errorf("no active stream in chat %d", chatID)
+	}
+
+	log.Printf(">> Changing stream - chatID: %d, file: %s, offset: %ds", chatID, filePath, offset)
+
+	media := c.withPresentation(chatID, mediaDescription(filePath, video, offset, c.Effects(chatID), c.Quality(chatID)))
+	a := c.assistant(chatID)
+	if err := a.ntg.SetStreamSources(chatID, ntg.CaptureStream, media); err != nil {
+		return fmt.Errorf("SetStreamSources failed: %w", err)
+	}
+
+	// Fresh sources start playing, but a muted chat stays muted
+	muted := c.IsMuted(chatID)
+	if muted {
+		if _, err := a.ntg.Mute(chatID); err != nil {
+			log.Printf(">> Failed to keep chat %d muted: %v", chatID, err)
+			muted = false
+		}
+	}
+	paused := c.holdPause(a, chatID)
+
+	c.activeSessionsMu.Lock()
+	now := time.Now()
+	session := &VCSession{
+		ChatID:    chatID,
+		FilePath:  filePath,
+		IsVideo:   video,
+		StartTime: now,
+		Offset:    offset,
+		Muted:     muted,
+	}
+	if paused {
+		session.pausedAt = now
+	}
+	// The call and its presentation outlive the sources
+	if prev, ok := c.activeSessions[chatID]; ok {
+		session.groupCall = prev.groupCall
+		session.started = prev.started
+		session.Presentation = prev.Presentation
+		session.presentationStart = prev.presentationStart
+	}
+	c.activeSessions[chatID] = session
+	c.activeSessionsMu.Unlock()
+
+	return nil
+}
+
+// mediaDescription builds the ntgcalls sources for a file
+// Microphone = audio input, Camera = video input
+// A non-zero offset or any effect pipes the file through ffmpeg instead,
+// a bitrate cap does so for the video only
+func mediaDescription(filePath string, video bool, offset int, effects AudioEffects, quality StreamQuality) ntg.MediaDescription {
+	audioSource, audioInput := ntg.MediaSourceFFmpeg, filePath
+	videoSource, videoInput := ntg.MediaSourceFFmpeg, filePath
+	if offset > 0 || !effects.IsDefault() {
+		audioSource = ntg.MediaSourceShell
+		audioInput = ffmpegAudioCommand(filePath, offset, effects, quality)
+	}
+	if offset > 0 || !effects.IsDefault() || quality.Bitrate > 0 {
+		videoSource = ntg.MediaSourceShell
+		videoInput = ffmpegVideoCommand(filePath, offset, effects, quality)
+	}
+
+	media := ntg.MediaDescription{
+		Microphone: &ntg.AudioDescription{
+			MediaSource:  audioSource,
+			Input:        audioInput,
+			SampleRate:   uint32(quality.SampleRate),
+			ChannelCount: uint8(quality.Channels),
+		},
+	}
+
+	if video {
+		media.Camera = &ntg.VideoDescription{
+			MediaSource: videoSource,
+			Input:       videoInput,
+			Width:       int16(quality.Width),
+			Height:      int16(quality.Height),
+			Fps:         uint8(quality.Fps),
+		}
+	}
+
+	return media
+}
+
+// LeaveVC leaves the voice chat of a chat
+// A call the assistant started is ended instead when the chat wants that
 func (c *Calls) LeaveVC(chatID int64) error {
+	// Leaving is attempted even when the player doesn't think it is in the call
+	if _, err := c.players.fire(chatID, EventEnd, ""); err == nil {
+		defer c.players.fire(chatID, EventEnded, "")
+	}
+
 	c.activeSessionsMu.Lock()
+	session, ok := c.activeSessions[chatID]
 	delete(c.activeSessions, chatID)
 	c.activeSessionsMu.Unlock()
+	c.forgetListeners(chatID)
+	c.forgetBroadcast(chatID)
+	if c.Recording(chatID) != nil {
+		go c.finishRecording(chatID, "voice chat ended")
+	}
+
+	a := c.assistant(chatID)
+	c.unassign(chatID)
 
-	groupCall, err := c.GetInputGroupCall(chatID)
-	if err == nil {
-		c.client.PhoneLeaveGroupCall(tg.InputGroupCall(groupCall), 0)
+	groupCall, err := c.inputGroupCall(a, chatID)
+	switch {
+	case err != nil:
+	case ok && session.started && c.VCSettings(chatID).EndCall:
+		log.Printf(">> Ending the group call of chat %d", chatID)
+		a.Client.PhoneDiscardGroupCall(groupCall)
+	default:
+		a.Client.PhoneLeaveGroupCall(tg.InputGroupCall(groupCall), 0)
 	}
 
-	return c.ntg.Stop(chatID)
+	stopErr := a.ntg.Stop(chatID)
+	if ok && session.Presentation != "" {
+		c.presentationEnded(chatID, session.Presentation)
+	}
+	return stopErr
 }
 
+// PauseVC pauses the stream of a chat
 func (c *Calls) PauseVC(chatID int64) error {
-	_, err := c.ntg.Pause(chatID)
+	if !c.IsActive(chatID) {
+		return fmt.Errorf("no active stream in chat %d", chatID)
+	}
+	if _, err := c.assistant(chatID).ntg.Pause(chatID); err != nil {
+		return err
+	}
+
+	// The state and the pause clock change together so positions never see one without the other
+	c.activeSessionsMu.Lock()
+	defer c.activeSessionsMu.Unlock()
+	from, err := c.players.fire(chatID, EventPause, "")
+	if s, ok := c.activeSessions[chatID]; ok && err == nil && from == StatePlaying {
+		s.pausedAt = time.Now()
+	}
 	return err
 }
 
+// ResumeVC resumes a paused stream
 func (c *Calls) ResumeVC(chatID int64) error {
-	_, err := c.ntg.Resume(chatID)
+	if !c.IsActive(chatID) {
+		return fmt.Errorf("no active stream in chat %d", chatID)
+	}
+	if _, err := c.assistant(chatID).ntg.Resume(chatID); err != nil {
+		return err
+	}
+
+	c.activeSessionsMu.Lock()
+	defer c.activeSessionsMu.Unlock()
+	from, err := c.players.fire(chatID, EventResume, "")
+	if s, ok := c.activeSessions[chatID]; ok && err == nil && from == StatePaused {
+		s.pausedFor += time.Since(s.pausedAt)
+		s.pausedAt = time.Time{}
+	}
 	return err
 }
 
+// holdPause pauses fresh stream sources again while the player of a chat is paused
+// New sources always start playing, it reports whether they were paused
+func (c *Calls) holdPause(a *Assistant, chatID int64) bool {
+	if !c.IsPaused(chatID) {
+		return false
+	}
+	if _, err := a.ntg.Pause(chatID); err != nil {
+		log.Printf(">> Failed to keep chat %d paused: %v", chatID, err)
+		c.players.fire(chatID, EventResume, "")
+		return false
+	}
+	return true
+}
+
+// MuteVC mutes the stream of a chat without pausing it
 func (c *Calls) MuteVC(chatID int64) error {
-	_, err := c.ntg.Mute(chatID)
-	return err
+	if !c.IsActive(chatID) {
+		return fmt.Errorf("no active stream in chat %d", chatID)
+	}
+	if _, err := c.assistant(chatID).ntg.Mute(chatID); err != nil {
+		return err
+	}
+	c.updateSession(chatID, func(s *VCSession) { s.Muted = true })
+	return nil
 }
 
+// UnmuteVC unmutes a muted stream
 func (c *Calls) UnmuteVC(chatID int64) error {
-	_, err := c.ntg.Unmute(chatID)
-	return err
+	if !c.IsActive(chatID) {
+		return fmt.Errorf("no active stream in chat %d", chatID)
+	}
+	if _, err := c.assistant(chatID).ntg.Unmute(chatID); err != nil {
+		return err
+	}
+	c.updateSession(chatID, func(s *VCSession) { s.Muted = false })
+	return nil
 }
 
-func (c *Calls) GetPing() int64 { return 50 }
+// IsPaused reports whether the stream of a chat is paused
+func (c *Calls) IsPaused(chatID int64) bool {
+	return c.players.State(chatID).State == StatePaused
+}
 
-func (c *Calls) IsActive(chatID int64) bool {
+// IsMuted reports whether the stream of a chat is muted
+func (c *Calls) IsMuted(chatID int64) bool {
 	c.activeSessionsMu.RLock()
 	defer c.activeSessionsMu.RUnlock()
-	_, ok := c.activeSessions[chatID]
-	return ok
+	s, ok := c.activeSessions[chatID]
+	return ok && s.Muted
+}
+
+// SetEffects changes the audio effects used for the streams of a chat
+// A running stream keeps its old sources until it is restarted
+func (c *Calls) SetEffects(chatID int64, effects AudioEffects) error {
+	clock, hasClock := c.streamClock(chatID)
+	speed := c.Effects(chatID).PlaybackSpeed()
+
+	c.activeSessionsMu.Lock()
+	defer c.activeSessionsMu.Unlock()
+
+	// Fold the time played so far at the old speed into the offset
+	if s, ok := c.activeSessions[chatID]; ok {
+		now := time.Now()
+		paused := c.IsPaused(chatID)
+		if hasClock {
+			s.Offset = s.streamPosition(clock, speed, paused)
+			s.clockBase = clock
+		} else {
+			s.Offset = s.position(speed, paused)
+		}
+		s.StartTime = now
+		s.pausedFor = 0
+		if paused {
+			s.pausedAt = now
+		}
+	}
+
+	c.effectsStoreMu.RLock()
+	defer c.effectsStoreMu.RUnlock()
+	if c.effectsStore == nil {
+		return errors.New("no effects store set")
+	}
+	return c.effectsStore.SetAudioEffects(chatID, effects.BassBoost, effects.PlaybackSpeed())
+}
+
+// SetEffectsStore sets where the audio effects of each chat are kept
+func (c *Calls) SetEffectsStore(store EffectsStore) {
+	c.effectsStoreMu.Lock()
+	defer c.effectsStoreMu.Unlock()
+	c.effectsStore = store
+}
+
+// SetQualitySource sets where the stream quality of each chat is read from
+// New stream sources pick it up, running ones keep theirs until restarted
+func (c *Calls) SetQualitySource(source QualitySource) {
+	c.qualitySourceMu.Lock()
+	defer c.qualitySourceMu.Unlock()
+	c.qualitySource = source
+}
+
+// Quality returns the stream quality of a chat
+func (c *Calls) Quality(chatID int64) StreamQuality {
+	c.qualitySourceMu.RLock()
+	source := c.qualitySource
+	c.qualitySourceMu.RUnlock()
+
+	if source == nil {
+		return DefaultQuality()
+	}
+	return source(chatID).withDefaults()
+}
+
+// Effects returns the audio effects of a chat
+func (c *Calls) Effects(chatID int64) AudioEffects {
+	c.effectsStoreMu.RLock()
+	defer c.effectsStoreMu.RUnlock()
+	if c.effectsStore == nil {
+		return AudioEffects{BassBoost: 0, Speed: 1.0}
+	}
+	return c.effectsStore.GetAudioEffects(chatID)
+}
+
+// Position returns the current track position of a chat in seconds
+// It follows the ntgcalls stream clock, which stands still while paused,
+// and falls back to the wall clock when ntgcalls can't tell
+func (c *Calls) Position(chatID int64) int {
+	if !c.IsActive(chatID) {
+		return 0
+	}
+	clock, hasClock := c.streamClock(chatID)
+	speed := c.Effects(chatID).PlaybackSpeed()
+
+	c.activeSessionsMu.RLock()
+	defer c.activeSessionsMu.RUnlock()
+	s, ok := c.activeSessions[chatID]
+	if !ok {
+		return 0
+	}
+	paused := c.IsPaused(chatID)
+	if !hasClock {
+		return s.position(speed, paused)
+	}
+	return s.streamPosition(clock, speed, paused)
+}
+
+// streamClock returns the seconds ntgcalls has streamed from the current sources
+func (c *Calls) streamClock(chatID int64) (int, bool) {
+	played, err := c.assistant(chatID).ntg.Time(chatID, ntg.CaptureStream)
+	if err != nil {
+		return 0, false
+	}
+	return int(played), true
+}
+
+func (c *Calls) updateSession(chatID int64, update func(*VCSession)) {
+	c.activeSessionsMu.Lock()
+	defer c.activeSessionsMu.Unlock()
+	if s, ok := c.activeSessions[chatID]; ok {
+		update(s)
+	}
+}
+
+func (c *Calls) GetPing() int64 { return 50 }
+
+// IsActive reports whether the player of a chat is playing or paused
+func (c *Calls) IsActive(chatID int64) bool {
+	return c.players.State(chatID).State.Active()
+}
+
+// PlayerState returns the state snapshot of the player of a chat
+func (c *Calls) PlayerState(chatID int64) ChatState {
+	return c.players.State(chatID)
+}
+
+// Players returns the state machines every view of playback reads from
+func (c *Calls) Players() *PlayerStates {
+	return c.players
 }
 
 // GetInputGroupCall returns *tg.InputGroupCallObj for a chat
 func (c *Calls) GetInputGroupCall(chatID int64) (*tg.InputGroupCallObj, error) {
-	peer, err := c.client.ResolvePeer(chatID)
+	return c.inputGroupCall(c.assistant(chatID), chatID)
+}
+
+// inputGroupCall looks up the group call of a chat as seen by an assistant
+func (c *Calls) inputGroupCall(a *Assistant, chatID int64) (*tg.InputGroupCallObj, error) {
+	peer, err := a.Client.ResolvePeer(chatID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve peer (chatID: %d): %w", chatID, err)
 	}
@@ -212,7 +729,7 @@ func (c *Calls) GetInputGroupCall(chatID int64) (*tg.InputGroupCallObj, error) {
 
 	switch p := peer.(type) {
 	case *tg.InputPeerChannel:
-		fullChannel, err := c.client.ChannelsGetFullChannel(&tg.InputChannelObj{
+		fullChannel, err := a.Client.ChannelsGetFullChannel(&tg.InputChannelObj{
 			ChannelID:  p.ChannelID,
 			AccessHash: p.AccessHash,
 		})
@@ -224,7 +741,7 @@ func (c *Calls) GetInputGroupCall(chatID int64) (*tg.InputGroupCallObj, error) {
 			return nil, fmt.Errorf("unexpected FullChat type: %T", fullChannel.FullChat)
 		}
 		if fullChan.Call == nil {
-			return nil, fmt.Errorf("❌ No active Voice Chat! Start VC from group settings first.")
+			return nil, errNoGroupCall
 		}
 		callObj, ok := fullChan.Call.(*tg.InputGroupCallObj)
 		if !ok {
@@ -234,7 +751,7 @@ func (c *Calls) GetInputGroupCall(chatID int64) (*tg.InputGroupCallObj, error) {
 		return callObj, nil
 
 	case *tg.InputPeerChat:
-		fullChat, err := c.client.MessagesGetFullChat(p.ChatID)
+		fullChat, err := a.Client.MessagesGetFullChat(p.ChatID)
 		if err != nil {
 			return nil, fmt.Errorf("MessagesGetFullChat failed: %w", err)
 		}
@@ -243,7 +760,7 @@ func (c *Calls) GetInputGroupCall(chatID int64) (*tg.InputGroupCallObj, error) {
 			return nil, fmt.Errorf("unexpected FullChat type: %T", fullChat.FullChat)
 		}
 		if chatFull.Call == nil {
-			return nil, fmt.Errorf("❌ No active Voice Chat! Start VC from group settings first.")
+			return nil, errNoGroupCall
 		}
 		callObj, ok := chatFull.Call.(*tg.InputGroupCallObj)
 		if !ok {
@@ -257,14 +774,66 @@ func (c *Calls) GetInputGroupCall(chatID int64) (*tg.InputGroupCallObj, error) {
 	}
 }
 
+// Stop ends every call while the assistants are still connected
+// The ntgcalls clients stay allocated until Free
 func (c *Calls) Stop() {
-	c.activeSessionsMu.RLock()
-	ids := make([]int64, 0, len(c.activeSessions))
-	for id := range c.activeSessions {
-		ids = append(ids, id)
+	// Recordings are finished first so their files are complete
+	c.recordingsMu.Lock()
+	recorded := make([]int64, 0, len(c.recordings))
+	for id := range c.recordings {
+		recorded = append(recorded, id)
+	}
+	c.recordingsMu.Unlock()
+	for _, id := range recorded {
+		c.finishRecording(id, "bot stopped")
+	}
+
+	c.privateCallsMu.Lock()
+	users := make([]int64, 0, len(c.privateCalls))
+	for id := range c.privateCalls {
+		users = append(users, id)
 	}
-	c.activeSessionsMu.RUnlock()
-	for _, id := range ids {
-		c.ntg.Stop(id)
+	c.privateCallsMu.Unlock()
+	for _, id := range users {
+		c.HangUp(id)
+	}
+
+	// Chats are marked ending first so the closing calls aren't taken for dropped ones
+	for _, state := range c.players.Active() {
+		c.players.fire(state.ChatID, EventEnd, "")
+	}
+	c.activeSessionsMu.Lock()
+	sessions := c.activeSessions
+	c.activeSessions = make(map[int64]*VCSession)
+	c.activeSessionsMu.Unlock()
+	for id, s := range sessions {
+		a := c.assistant(id)
+		a.ntg.Stop(id)
+		if s.groupCall != nil {
+			a.Client.PhoneLeaveGroupCall(tg.InputGroupCall(s.groupCall), 0)
+		}
+		c.unassign(id)
+		c.forgetBroadcast(id)
+		c.players.fire(id, EventEnded, "")
+		if s.Presentation != "" {
+			c.presentationEnded(id, s.Presentation)
+		}
+	}
+
+	c.broadcastsMu.Lock()
+	for _, a := range c.assistants {
+		for dc, sender := range a.streamSenders {
+			sender.Terminate()
+			delete(a.streamSenders, dc)
+		}
+	}
+	c.broadcastsMu.Unlock()
+}
+
+// Free releases the ntgcalls clients, the Calls can't be used afterwards
+// Only call it once nothing can reach the Calls anymore
+func (c *Calls) Free() {
+	for _, a := range c.assistants {
+		a.ntg.Free()
 	}
 }
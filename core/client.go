@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 
 	"shizumusic/config"
@@ -11,10 +12,14 @@ import (
 	tg "github.com/amarnathcjd/gogram/telegram"
 )
 
+// maxAssistants is how many assistant sessions are read, STRING_SESSION to STRING_SESSION10
+const maxAssistants = 10
+
 // Client holds both bot and user clients
 type Client struct {
 	BotClient  *tg.Client
-	UserClient *tg.Client
+	UserClient *tg.Client   // Primary assistant from STRING_SESSION
+	Assistants []*tg.Client // Every online assistant, the primary first
 	Config     *config.Config
 }
 
@@ -60,7 +65,8 @@ func (c *Client) StartBot(ctx context.Context) error {
 	return nil
 }
 
-// StartUser starts the assistant client
+// StartUser starts the assistant clients
+// STRING_SESSION is required, STRING_SESSION2 to STRING_SESSION10 add more assistants
 func (c *Client) StartUser(ctx context.Context) error {
 	if c.Config.StringSession == "" {
 		log.Fatal("❌ No STRING_SESSION provided for assistant client.")
@@ -68,45 +74,73 @@ func (c *Client) StartUser(ctx context.Context) error {
 
 	log.Println(">> Starting assistant client...")
 
-	// Create user client with string session from config
+	// The primary assistant must come up
+	client, err := startAssistant(c.Config, "./cache/assistant.session", c.Config.StringSession)
+	if err != nil {
+		log.Fatal("❌ " + err.Error())
+	}
+	c.UserClient = client
+	c.Assistants = []*tg.Client{client}
+
+	// Extra assistants are optional, a broken one is skipped
+	for i := 2; i <= maxAssistants; i++ {
+		session := os.Getenv(fmt.Sprintf("STRING_SESSION%d", i))
+		if session == "" {
+			continue
+		}
+		client, err := startAssistant(c.Config, fmt.Sprintf("./cache/assistant%d.session", i), session)
+		if err != nil {
+			log.Printf("❌ Skipping STRING_SESSION%d: %v", i, err)
+			continue
+		}
+		c.Assistants = append(c.Assistants, client)
+	}
+	log.Printf(">> %d assistant(s) online", len(c.Assistants))
+
+	// Join support channels
+	go c.joinChannels()
+
+	return nil
+}
+
+// startAssistant logs in an assistant account from a string session
+func startAssistant(cfg *config.Config, sessionFile, stringSession string) (*tg.Client, error) {
 	client, err := tg.NewClient(tg.ClientConfig{
-		AppID:         c.Config.APIID,
-		AppHash:       c.Config.APIHash,
-		Session:       "./cache/assistant.session", // Proper session file path
+		AppID:         cfg.APIID,
+		AppHash:       cfg.APIHash,
+		Session:       sessionFile,
 		LogLevel:      tg.LogInfo,
-		StringSession: c.Config.StringSession,
+		StringSession: stringSession,
 	})
 	if err != nil {
-		log.Fatal("❌ Failed to create assistant client: " + err.Error())
+		return nil, fmt.Errorf("failed to create assistant client: %w", err)
 	}
 
 	// Connect
 	if err := client.Connect(); err != nil {
-		log.Fatal("❌ Failed to connect assistant client: " + err.Error())
+		return nil, fmt.Errorf("failed to connect assistant client: %w", err)
 	}
 
 	// Get user info
 	me, err := client.GetMe()
 	if err != nil {
-		log.Fatal("❌ Failed to get assistant info: " + err.Error())
+		client.Stop()
+		return nil, fmt.Errorf("failed to get assistant info: %w", err)
 	}
 
-	c.UserClient = client
 	log.Printf(">> Assistant @%s is online now!", me.Username)
-
-	// Join support channels
-	go c.joinChannels()
-
-	return nil
+	return client, nil
 }
 
 // joinChannels joins support channels
 func (c *Client) joinChannels() {
 	channels := []string{"PBX_CHAT"}
 	
-	for _, channel := range channels {
-		if _, err := c.UserClient.JoinChannel(channel); err != nil {
-			log.Printf("Warning: Failed to join @%s: %v", channel, err)
+	for _, assistant := range c.Assistants {
+		for _, channel := range channels {
+			if _, err := assistant.JoinChannel(channel); err != nil {
+				log.Printf("Warning: Failed to join @%s: %v", channel, err)
+			}
 		}
 	}
 }
@@ -132,14 +166,25 @@ func (c *Client) SendToLogger(text string, photo string) error {
 
 // Stop gracefully stops both clients
 func (c *Client) Stop() {
+	c.StopBot()
+	c.StopAssistants()
+}
+
+// StopBot disconnects the bot so no more commands reach the handlers
+func (c *Client) StopBot() {
 	if c.BotClient != nil {
 		log.Println(">> Disconnecting bot client...")
 		c.BotClient.Stop()
 	}
+}
 
-	if c.UserClient != nil {
-		log.Println(">> Disconnecting assistant client...")
-		c.UserClient.Stop()
+// StopAssistants disconnects every assistant client
+func (c *Client) StopAssistants() {
+	if len(c.Assistants) > 0 {
+		log.Println(">> Disconnecting assistant clients...")
+		for _, assistant := range c.Assistants {
+			assistant.Stop()
+		}
 	}
 }
 
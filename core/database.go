@@ -25,13 +25,16 @@ type Database struct {
 	chats        *mongo.Collection
 	favorites    *mongo.Collection
 	gbanDB       *mongo.Collection
+	queues       *mongo.Collection
 	songsDB      *mongo.Collection
 	sudoUsers    *mongo.Collection
 	users        *mongo.Collection
 
+	// Player states of the calls manager, read for the active voice chats
+	players   *PlayerStates
+	playersMu sync.RWMutex
+
 	// Local caches (in-memory)
-	activeVC      []ActiveVC
-	activeVCMutex sync.RWMutex
 	inactive      map[int64]time.Time
 	inactiveMutex sync.RWMutex
 	loop          map[int64]int
@@ -40,6 +43,18 @@ type Database struct {
 	watcherMutex  sync.RWMutex
 	audioEffects  map[int64]AudioEffects
 	effectsMutex  sync.RWMutex
+	quality       map[int64]StreamQuality
+	qualityMutex  sync.RWMutex
+	assistants    map[int64]int64
+	assistantsMu  sync.RWMutex
+	vcSettings    map[int64]VCSettings
+	vcSettingsMu  sync.RWMutex
+	autoendCache  *bool // nil until read from the database
+	autoendMu     sync.RWMutex
+
+	// Chats whose player state changed since the last DirtyChats call
+	dirty      map[int64]bool
+	dirtyMutex sync.Mutex
 }
 
 // ActiveVC represents an active voice chat
@@ -55,6 +70,60 @@ type AudioEffects struct {
 	Speed     float64 `bson:"speed"`
 }
 
+// AuthUser represents a user authorized to control playback in a chat
+type AuthUser struct {
+	ChatID    int64  `bson:"chat_id"`
+	UserID    int64  `bson:"user_id"`
+	UserName  string `bson:"user_name"`
+	AdminID   int64  `bson:"admin_id"`
+	AdminName string `bson:"admin_name"`
+	AuthDate  string `bson:"auth_date"`
+}
+
+// Favorite represents a track in a user's favorites
+type Favorite struct {
+	UserID   int64  `bson:"user_id"`
+	TrackID  string `bson:"track_id"`
+	Title    string `bson:"title"`
+	Duration string `bson:"duration"`
+	AddDate  string `bson:"add_date"`
+}
+
+// IsDefault reports whether the effects leave playback untouched
+func (e AudioEffects) IsDefault() bool {
+	return e.BassBoost == 0 && e.PlaybackSpeed() == 1.0
+}
+
+// PlaybackSpeed returns the playback speed, treating unset as normal speed
+func (e AudioEffects) PlaybackSpeed() float64 {
+	if e.Speed <= 0 {
+		return 1.0
+	}
+	return e.Speed
+}
+
+// QueueTrack is a queued track as stored in the queues collection
+type QueueTrack struct {
+	UserID   int64  `bson:"user_id"`
+	Duration string `bson:"duration"`
+	File     string `bson:"file"`
+	Title    string `bson:"title"`
+	User     string `bson:"user"`
+	VideoID  string `bson:"video_id"`
+	VCType   string `bson:"vc_type"`
+	Played   int    `bson:"played"`
+}
+
+// PlaybackState is the persisted queue and player state of a chat
+type PlaybackState struct {
+	ChatID    int64        `bson:"chat_id"`
+	Tracks    []QueueTrack `bson:"tracks"`
+	Loop      int          `bson:"loop"`
+	Effects   AudioEffects `bson:"effects"`
+	Active    bool         `bson:"active"`
+	UpdatedAt time.Time    `bson:"updated_at"`
+}
+
 // User represents a user document
 type User struct {
 	UserID             int64     `bson:"user_id"`
@@ -96,14 +165,18 @@ func NewDatabase(uri string) (*Database, error) {
 		chats:        db.Collection("chats"),
 		favorites:    db.Collection("favorites"),
 		gbanDB:       db.Collection("gban_db"),
+		queues:       db.Collection("queues"),
 		songsDB:      db.Collection("songsdb"),
 		sudoUsers:    db.Collection("sudousers"),
 		users:        db.Collection("users"),
-		activeVC:     []ActiveVC{{ChatID: 0, JoinTime: time.Now(), VCType: "voice"}},
 		inactive:     make(map[int64]time.Time),
 		loop:         make(map[int64]int),
 		watcher:      make(map[int64]map[string]bool),
 		audioEffects: make(map[int64]AudioEffects),
+		quality:      make(map[int64]StreamQuality),
+		assistants:   make(map[int64]int64),
+		vcSettings:   make(map[int64]VCSettings),
+		dirty:        make(map[int64]bool),
 	}, nil
 }
 
@@ -208,61 +281,86 @@ func (d *Database) TotalUsersCount() (int64, error) {
 	return d.users.CountDocuments(ctx, bson.M{})
 }
 
-// ========== ACTIVE VC OPERATIONS (Local) ==========
+// TotalChatsCount returns total chat count
+func (d *Database) TotalChatsCount() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-// AddActiveVC adds active voice chat
-func (d *Database) AddActiveVC(chatID int64, vcType string) error {
-	d.activeVCMutex.Lock()
-	defer d.activeVCMutex.Unlock()
+	return d.chats.CountDocuments(ctx, bson.M{})
+}
 
-	// Check if already exists
-	for _, vc := range d.activeVC {
-		if vc.ChatID == chatID {
-			return nil
-		}
-	}
+// ========== ACTIVE VC OPERATIONS (Local) ==========
 
-	d.activeVC = append(d.activeVC, ActiveVC{
-		ChatID:   chatID,
-		JoinTime: time.Now(),
-		VCType:   vcType,
+// SetPlayerStates makes the player states of the calls manager the source of the active voice chats
+// Every state change marks the chat dirty, chats going idle stop counting as inactive
+func (d *Database) SetPlayerStates(players *PlayerStates) {
+	d.playersMu.Lock()
+	d.players = players
+	d.playersMu.Unlock()
+
+	players.OnChange(func(chatID int64, from, to PlayerState) {
+		d.markDirty(chatID)
+		if to == StateIdle {
+			d.ClearInactive(chatID)
+		}
 	})
-	return nil
 }
 
-// IsActiveVC checks if VC is active
+func (d *Database) playerStates() *PlayerStates {
+	d.playersMu.RLock()
+	defer d.playersMu.RUnlock()
+	return d.players
+}
+
+// IsActiveVC checks if the player of a chat is playing or paused
 func (d *Database) IsActiveVC(chatID int64) (bool, error) {
-	d.activeVCMutex.RLock()
-	defer d.activeVCMutex.RUnlock()
+	players := d.playerStates()
+	if players == nil {
+		return false, nil
+	}
+	return players.State(chatID).State.Active(), nil
+}
 
-	for _, vc := range d.activeVC {
-		if vc.ChatID == chatID {
-			return true, nil
-		}
+// GetActiveVC gets all active VCs
+func (d *Database) GetActiveVC() []ActiveVC {
+	players := d.playerStates()
+	if players == nil {
+		return nil
 	}
-	return false, nil
+
+	var active []ActiveVC
+	for _, state := range players.Active() {
+		active = append(active, ActiveVC{
+			ChatID:   state.ChatID,
+			JoinTime: state.JoinTime,
+			VCType:   state.VCType,
+		})
+	}
+	return active
 }
 
-// RemoveActiveVC removes active VC
-func (d *Database) RemoveActiveVC(chatID int64) error {
-	d.activeVCMutex.Lock()
-	defer d.activeVCMutex.Unlock()
+// ========== INACTIVE VC OPERATIONS (Local) ==========
 
-	for i, vc := range d.activeVC {
-		if vc.ChatID == chatID {
-			d.activeVC = append(d.activeVC[:i], d.activeVC[i+1:]...)
-			break
-		}
+// MarkInactive records that a voice chat went idle and returns since when
+// The first recorded time is kept until ClearInactive is called
+func (d *Database) MarkInactive(chatID int64) time.Time {
+	d.inactiveMutex.Lock()
+	defer d.inactiveMutex.Unlock()
+
+	if since, ok := d.inactive[chatID]; ok {
+		return since
 	}
-	return nil
+	now := time.Now()
+	d.inactive[chatID] = now
+	return now
 }
 
-// GetActiveVC gets all active VCs
-func (d *Database) GetActiveVC() []ActiveVC {
-	d.activeVCMutex.RLock()
-	defer d.activeVCMutex.RUnlock()
+// ClearInactive forgets that a voice chat was idle
+func (d *Database) ClearInactive(chatID int64) {
+	d.inactiveMutex.Lock()
+	defer d.inactiveMutex.Unlock()
 
-	return append([]ActiveVC{}, d.activeVC...)
+	delete(d.inactive, chatID)
 }
 
 // ========== LOOP OPERATIONS (Local) ==========
@@ -273,6 +371,7 @@ func (d *Database) SetLoop(chatID int64, count int) error {
 	defer d.loopMutex.Unlock()
 
 	d.loop[chatID] = count
+	d.markDirty(chatID)
 	return nil
 }
 
@@ -295,6 +394,7 @@ func (d *Database) SetAudioEffects(chatID int64, bassBoost int, speed float64) e
 		BassBoost: bassBoost,
 		Speed:     speed,
 	}
+	d.markDirty(chatID)
 	return nil
 }
 
@@ -309,6 +409,227 @@ func (d *Database) GetAudioEffects(chatID int64) AudioEffects {
 	return AudioEffects{BassBoost: 0, Speed: 1.0}
 }
 
+// ========== STREAM QUALITY ==========
+
+// GetStreamQuality gets the stream quality of a chat from its settings
+// Chats without a saved profile use DefaultQuality
+func (d *Database) GetStreamQuality(chatID int64) StreamQuality {
+	d.qualityMutex.RLock()
+	quality, ok := d.quality[chatID]
+	d.qualityMutex.RUnlock()
+	if ok {
+		return quality
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var result struct {
+		Quality *StreamQuality `bson:"quality"`
+	}
+	err := d.chats.FindOne(ctx, bson.M{"chat_id": chatID}).Decode(&result)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Printf(">> Loading stream quality failed for chat %d: %v", chatID, err)
+		return DefaultQuality()
+	}
+
+	quality = DefaultQuality()
+	if result.Quality != nil {
+		quality = result.Quality.withDefaults()
+	}
+
+	d.qualityMutex.Lock()
+	d.quality[chatID] = quality
+	d.qualityMutex.Unlock()
+	return quality
+}
+
+// SetStreamQuality saves the stream quality of a chat in its settings
+func (d *Database) SetStreamQuality(chatID int64, quality StreamQuality) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := d.chats.UpdateOne(
+		ctx,
+		bson.M{"chat_id": chatID},
+		bson.M{"$set": bson.M{"quality": quality}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	d.qualityMutex.Lock()
+	d.quality[chatID] = quality
+	d.qualityMutex.Unlock()
+	return nil
+}
+
+// ========== ASSISTANTS ==========
+
+// GetChatAssistant gets the user ID of the assistant a chat was last served by, 0 when none
+func (d *Database) GetChatAssistant(chatID int64) int64 {
+	d.assistantsMu.RLock()
+	assistantID, ok := d.assistants[chatID]
+	d.assistantsMu.RUnlock()
+	if ok {
+		return assistantID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var result struct {
+		Assistant int64 `bson:"assistant"`
+	}
+	err := d.chats.FindOne(ctx, bson.M{"chat_id": chatID}).Decode(&result)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Printf(">> Loading assistant failed for chat %d: %v", chatID, err)
+		return 0
+	}
+
+	d.assistantsMu.Lock()
+	d.assistants[chatID] = result.Assistant
+	d.assistantsMu.Unlock()
+	return result.Assistant
+}
+
+// SetChatAssistant saves the assistant a chat is served by in its settings
+func (d *Database) SetChatAssistant(chatID, assistantID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := d.chats.UpdateOne(
+		ctx,
+		bson.M{"chat_id": chatID},
+		bson.M{"$set": bson.M{"assistant": assistantID}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	d.assistantsMu.Lock()
+	d.assistants[chatID] = assistantID
+	d.assistantsMu.Unlock()
+	return nil
+}
+
+// ========== VOICE CHAT SETTINGS ==========
+
+// GetVCSettings gets the group call settings of a chat, all off when never set
+func (d *Database) GetVCSettings(chatID int64) VCSettings {
+	d.vcSettingsMu.RLock()
+	settings, ok := d.vcSettings[chatID]
+	d.vcSettingsMu.RUnlock()
+	if ok {
+		return settings
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var result struct {
+		Settings VCSettings `bson:"vc_settings"`
+	}
+	err := d.chats.FindOne(ctx, bson.M{"chat_id": chatID}).Decode(&result)
+	if err != nil && err != mongo.ErrNoDocuments {
+		log.Printf(">> Loading voice chat settings failed for chat %d: %v", chatID, err)
+		return VCSettings{}
+	}
+
+	d.vcSettingsMu.Lock()
+	d.vcSettings[chatID] = result.Settings
+	d.vcSettingsMu.Unlock()
+	return result.Settings
+}
+
+// SetVCSettings saves the group call settings of a chat
+func (d *Database) SetVCSettings(chatID int64, settings VCSettings) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := d.chats.UpdateOne(
+		ctx,
+		bson.M{"chat_id": chatID},
+		bson.M{"$set": bson.M{"vc_settings": settings}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	d.vcSettingsMu.Lock()
+	d.vcSettings[chatID] = settings
+	d.vcSettingsMu.Unlock()
+	return nil
+}
+
+// ========== PLAYBACK STATE ==========
+
+// markDirty flags the player state of a chat for the next write-behind flush
+func (d *Database) markDirty(chatID int64) {
+	d.dirtyMutex.Lock()
+	defer d.dirtyMutex.Unlock()
+
+	d.dirty[chatID] = true
+}
+
+// DirtyChats returns and resets the chats whose loop, effects or
+// active VC changed since the last call
+func (d *Database) DirtyChats() []int64 {
+	d.dirtyMutex.Lock()
+	defer d.dirtyMutex.Unlock()
+
+	chats := make([]int64, 0, len(d.dirty))
+	for chatID := range d.dirty {
+		chats = append(chats, chatID)
+	}
+	d.dirty = make(map[int64]bool)
+	return chats
+}
+
+// SavePlaybackState stores the queue and player state of a chat
+func (d *Database) SavePlaybackState(state PlaybackState) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	state.UpdatedAt = time.Now()
+	_, err := d.queues.ReplaceOne(
+		ctx,
+		bson.M{"chat_id": state.ChatID},
+		state,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// DeletePlaybackState drops the stored state of a chat
+func (d *Database) DeletePlaybackState(chatID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := d.queues.DeleteOne(ctx, bson.M{"chat_id": chatID})
+	return err
+}
+
+// GetPlaybackStates gets the stored state of every chat
+func (d *Database) GetPlaybackStates() ([]PlaybackState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := d.queues.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []PlaybackState
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // ========== SUDO USERS ==========
 
 // GetSudoUsers gets sudo users list
@@ -409,10 +730,122 @@ func (d *Database) IsAuthchat(chatID int64) (bool, error) {
 	return false, nil
 }
 
+// ========== AUTHUSERS ==========
+
+// IsAuthUser checks if user is authorized to control playback in a chat
+func (d *Database) IsAuthUser(chatID, userID int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := d.authusers.CountDocuments(ctx, bson.M{"chat_id": chatID, "user_id": userID})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetAuthUsers gets all authorized users of a chat
+func (d *Database) GetAuthUsers(chatID int64) ([]AuthUser, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := d.authusers.Find(ctx, bson.M{"chat_id": chatID})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []AuthUser
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ========== FAVORITES ==========
+
+// AddFavorite adds a track to user's favorites
+func (d *Database) AddFavorite(userID int64, trackID, title, duration string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := d.favorites.UpdateOne(
+		ctx,
+		bson.M{"user_id": userID, "track_id": trackID},
+		bson.M{"$set": bson.M{
+			"title":    title,
+			"duration": duration,
+			"add_date": time.Now().Format("02-01-2006 15:04"),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// GetFavorite gets a single favorite track of user
+func (d *Database) GetFavorite(userID int64, trackID string) (*Favorite, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var fav Favorite
+	err := d.favorites.FindOne(ctx, bson.M{"user_id": userID, "track_id": trackID}).Decode(&fav)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fav, nil
+}
+
+// GetFavorites gets track IDs of user's favorites, oldest first
+func (d *Database) GetFavorites(userID int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The upsert ObjectID is minted when a track is first added and kept
+	// on re-adds, so it orders favorites by insertion time
+	cursor, err := d.favorites.Find(
+		ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var favs []Favorite
+	if err := cursor.All(ctx, &favs); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(favs))
+	for _, fav := range favs {
+		ids = append(ids, fav.TrackID)
+	}
+	return ids, nil
+}
+
+// DeleteFavorites removes all favorites of user
+func (d *Database) DeleteFavorites(userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := d.favorites.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
+
 // ========== AUTOEND ==========
 
 // GetAutoend checks if autoend is enabled
+// The setting is read from the database once and cached afterwards
 func (d *Database) GetAutoend() (bool, error) {
+	d.autoendMu.RLock()
+	cached := d.autoendCache
+	d.autoendMu.RUnlock()
+	if cached != nil {
+		return *cached, nil
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -421,14 +854,42 @@ func (d *Database) GetAutoend() (bool, error) {
 	}
 
 	err := d.autoend.FindOne(ctx, bson.M{"autoend": "autoend"}).Decode(&result)
-	if err == mongo.ErrNoDocuments {
-		return true, nil // Default enabled
-	}
-	if err != nil {
+	if err != nil && err != mongo.ErrNoDocuments {
 		return true, err
 	}
 
-	return result.Status == "on", nil
+	enabled := err == mongo.ErrNoDocuments || result.Status == "on" // Default enabled
+	d.setAutoendCache(enabled)
+	return enabled, nil
+}
+
+func (d *Database) setAutoendCache(enabled bool) {
+	d.autoendMu.Lock()
+	defer d.autoendMu.Unlock()
+	d.autoendCache = &enabled
+}
+
+// SetAutoend turns automatic ending of idle voice chats on or off
+func (d *Database) SetAutoend(enabled bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	status := "off"
+	if enabled {
+		status = "on"
+	}
+
+	_, err := d.autoend.UpdateOne(
+		ctx,
+		bson.M{"autoend": "autoend"},
+		bson.M{"$set": bson.M{"status": status}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+	d.setAutoendCache(enabled)
+	return nil
 }
 
 // UpdateSongsCount increments songs count
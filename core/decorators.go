@@ -55,8 +55,11 @@ func AdminOnly(handler HandlerFunc) HandlerFunc {
 		}
 
 		// Check admin rights
-		// TODO: Implement get_user_rights check
-		// For now, allow all users (implement proper check later)
+		if !CanManageVC(m.Client, m.ChatID(), m.Sender.ID) {
+			m.Respond("**❌ Admins Only**\n\n" +
+				"You need to be an admin with manage voice chats rights to use this command!")
+			return nil
+		}
 
 		return handler(m)
 	}
@@ -89,17 +92,11 @@ func AuthOnly(db *Database) func(HandlerFunc) HandlerFunc {
 				return nil
 			}
 
-			// Check if authchat is enabled
-			isAuthChat, _ := db.IsAuthchat(m.Chat.ID)
-
-			if !isAuthChat {
-				// Bypass for sudo users
-				if config.Cfg.IsSudo(m.Sender.ID) {
-					return handler(m)
-				}
-
-				// TODO: Get authorized users list and check
-				// For now, allow all (implement proper check later)
+			// Admins with voice chat rights and authorized users pass
+			if !IsAuthorized(m.Client, db, m.ChatID(), m.Sender.ID) {
+				m.Respond("**❌ Not Authorized**\n\n" +
+					"You need to be an admin or an authorized user to use this command!")
+				return nil
 			}
 
 			return handler(m)
@@ -107,6 +104,39 @@ func AuthOnly(db *Database) func(HandlerFunc) HandlerFunc {
 	}
 }
 
+// CanManageVC checks if a user is an admin allowed to manage voice chats
+func CanManageVC(client *tg.Client, chatID, userID int64) bool {
+	member, err := client.GetChatMember(chatID, userID)
+	if err != nil {
+		return false
+	}
+
+	switch member.Status {
+	case tg.Creator:
+		return true
+	case tg.Admin:
+		return member.Rights != nil && member.Rights.ManageCall
+	}
+	return false
+}
+
+// IsAuthorized checks if a user may control playback in a chat
+// Everyone passes in auth chats, otherwise only sudo users,
+// admins with voice chat rights and authorized users do
+func IsAuthorized(client *tg.Client, db *Database, chatID, userID int64) bool {
+	if config.Cfg.IsSudo(userID) {
+		return true
+	}
+	if isAuthChat, _ := db.IsAuthchat(chatID); isAuthChat {
+		return true
+	}
+	if CanManageVC(client, chatID, userID) {
+		return true
+	}
+	isAuth, _ := db.IsAuthUser(chatID, userID)
+	return isAuth
+}
+
 // UserOnly allows all users except anonymous admins
 func UserOnly(handler HandlerFunc) HandlerFunc {
 	return func(m *tg.NewMessage) error {
@@ -170,8 +200,8 @@ type PlayContext struct {
 	IsVideo   bool
 	IsForce   bool
 	IsURL     string
-	IsTGAudio interface{}
-	IsTGVideo interface{}
+	IsTGAudio *TGMedia
+	IsTGVideo *TGMedia
 }
 
 // PlayWrapper validates and prepares playback context
@@ -223,10 +253,15 @@ func PlayWrapper(handler func(*tg.NewMessage, *PlayContext) error) HandlerFunc {
 
 		// Check for replied media - ReplyToMsgID is a METHOD
 		if m.ReplyToMsgID() != 0 {
-			// Get replied message
-			// TODO: Implement media detection from reply
-			// ctx.IsTGAudio = ...
-			// ctx.IsTGVideo = ...
+			if reply, err := m.GetReplyMessage(); err == nil {
+				if media := GetTGMedia(reply); media != nil {
+					if media.Video {
+						ctx.IsTGVideo = media
+					} else {
+						ctx.IsTGAudio = media
+					}
+				}
+			}
 		}
 
 		// Validate input
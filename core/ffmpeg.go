@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// bassGainStep is the gain in dB added per bass boost level
+const bassGainStep = 2
+
+// ffmpegAudioCommand builds a shell command piping raw PCM from offset seconds
+func ffmpegAudioCommand(filePath string, offset int, effects AudioEffects, quality StreamQuality) string {
+	filters := ""
+	if chain := audioFilters(effects); chain != "" {
+		filters = "-af " + shellQuote(chain) + " "
+	}
+	return fmt.Sprintf(
+		"ffmpeg -ss %d -i %s %s-f s16le -ac %d -ar %d -v quiet pipe:1",
+		offset, shellQuote(filePath), filters, quality.Channels, quality.SampleRate,
+	)
+}
+
+// ffmpegVideoCommand builds a shell command piping raw YUV frames from offset seconds
+// A bitrate first squeezes the frames through one encoder limited to it,
+// only the video takes that detour
+func ffmpegVideoCommand(filePath string, offset int, effects AudioEffects, quality StreamQuality) string {
+	filters := shellQuote(videoFilters(effects, quality))
+	if quality.Bitrate <= 0 {
+		return fmt.Sprintf(
+			"ffmpeg -ss %d -i %s -f rawvideo -r %d -pix_fmt yuv420p -vf %s -v quiet pipe:1",
+			offset, shellQuote(filePath), quality.Fps, filters,
+		)
+	}
+	return fmt.Sprintf(
+		"ffmpeg -ss %d -i %s -an -r %d -vf %s -c:v libx264 -preset ultrafast -tune zerolatency "+
+			"-b:v %[5]dk -maxrate %[5]dk -bufsize %[6]dk -f mpegts -v quiet pipe:1 | "+
+			"ffmpeg -i pipe:0 -f rawvideo -pix_fmt yuv420p -v quiet pipe:1",
+		offset, shellQuote(filePath), quality.Fps, filters, quality.Bitrate, quality.Bitrate*2,
+	)
+}
+
+// audioFilters builds the ffmpeg audio filter chain for the effects
+func audioFilters(effects AudioEffects) string {
+	var filters []string
+	if effects.BassBoost > 0 {
+		filters = append(filters, fmt.Sprintf("bass=g=%d", effects.BassBoost*bassGainStep))
+	}
+	if effects.PlaybackSpeed() != 1.0 {
+		filters = append(filters, fmt.Sprintf("atempo=%.2f", effects.PlaybackSpeed()))
+	}
+	return strings.Join(filters, ",")
+}
+
+// videoFilters builds the ffmpeg video filter chain, keeping frames in sync with atempo
+func videoFilters(effects AudioEffects, quality StreamQuality) string {
+	scale := fmt.Sprintf("scale=%d:%d", quality.Width, quality.Height)
+	if effects.PlaybackSpeed() == 1.0 {
+		return scale
+	}
+	return fmt.Sprintf("setpts=PTS/%.2f,%s", effects.PlaybackSpeed(), scale)
+}
+
+// shellQuote wraps a value in single quotes for safe use in a shell command
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// ProbeDuration reads the duration of a media file in seconds with ffprobe
+// Returns 0 when the duration cannot be read
+func ProbeDuration(ctx context.Context, filePath string) int {
+	output, err := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		filePath,
+	).Output()
+	if err != nil {
+		return 0
+	}
+
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0
+	}
+	return int(duration)
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+// errNoGroupCall is returned when a chat has no group call running
+var errNoGroupCall = errors.New("❌ No active Voice Chat! Start VC from group settings first, or let me start it with /autostart on.")
+
+// VCSettings controls how the assistant treats the group call of a chat
+type VCSettings struct {
+	AutoStart bool   `bson:"auto_start"` // start a group call when none is running
+	Title     string `bson:"title"`      // title of the calls the assistant starts
+	JoinAs    string `bson:"join_as"`    // @username or ID of a channel to join as, "" for the assistant
+	EndCall   bool   `bson:"end_call"`   // end a call the assistant started once playback is over
+}
+
+// VCSettingsSource returns the group call settings of a chat
+type VCSettingsSource func(chatID int64) VCSettings
+
+// SetVCSettingsSource sets where the group call settings of each chat are read from
+func (c *Calls) SetVCSettingsSource(source VCSettingsSource) {
+	c.vcSettingsMu.Lock()
+	defer c.vcSettingsMu.Unlock()
+	c.vcSettingsSource = source
+}
+
+// VCSettings returns the group call settings of a chat
+func (c *Calls) VCSettings(chatID int64) VCSettings {
+	c.vcSettingsMu.RLock()
+	source := c.vcSettingsSource
+	c.vcSettingsMu.RUnlock()
+
+	if source == nil {
+		return VCSettings{}
+	}
+	return source(chatID)
+}
+
+// startGroupCall starts the group call of a chat as its assistant
+// The assistant needs the manage video chats right
+func (c *Calls) startGroupCall(a *Assistant, chatID int64, title string) (*tg.InputGroupCallObj, error) {
+	peer, err := a.Client.ResolvePeer(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve peer (chatID: %d): %w", chatID, err)
+	}
+
+	_, err = a.Client.PhoneCreateGroupCall(&tg.PhoneCreateGroupCallParams{
+		Peer:     peer,
+		RandomID: rand.Int31(),
+		Title:    title,
+	})
+	if err != nil {
+		if tg.MatchError(err, "CHAT_ADMIN_REQUIRED") || tg.MatchError(err, "RIGHT_FORBIDDEN") {
+			return nil, &membershipError{fmt.Sprintf(
+				"No active voice chat and the assistant %s can't start one. Make it an admin with the \"Manage Video Chats\" right, or start the voice chat yourself.",
+				assistantName(a))}
+		}
+		return nil, fmt.Errorf("PhoneCreateGroupCall failed: %w", err)
+	}
+	log.Printf(">> %s started the group call of chat %d", describeAssistant(a), chatID)
+
+	return c.inputGroupCall(a, chatID)
+}
+
+// joinAsPeer resolves who an assistant shows up as in a group call
+// joinAs is the @username or ID of a channel, "" joins as the assistant itself
+func (c *Calls) joinAsPeer(a *Assistant, joinAs string) (tg.InputPeer, error) {
+	if joinAs == "" {
+		return c.getSelfPeer(a)
+	}
+
+	var peer tg.InputPeer
+	var err error
+	if id, parseErr := strconv.ParseInt(joinAs, 10, 64); parseErr == nil {
+		peer, err = a.Client.ResolvePeer(id)
+	} else {
+		peer, err = a.Client.ResolvePeer(strings.TrimPrefix(joinAs, "@"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't join as %s, the assistant %s must be able to see it: %w", joinAs, assistantName(a), err)
+	}
+	return peer, nil
+}
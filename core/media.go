@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+// TGMedia is a playable audio or video file sent on Telegram
+type TGMedia struct {
+	Message  *tg.NewMessage
+	Title    string
+	Duration int // Seconds, 0 when Telegram doesn't know it
+	Video    bool
+
+	doc      *tg.DocumentObj
+	fileName string
+}
+
+// GetTGMedia returns the playable media of a message
+// Audio, voice, video and audio/video documents qualify, anything else returns nil
+func GetTGMedia(m *tg.NewMessage) *TGMedia {
+	if m == nil || !m.IsMedia() {
+		return nil
+	}
+	doc := m.Document()
+	if doc == nil {
+		return nil
+	}
+
+	media := &TGMedia{Message: m, doc: doc}
+	playable := false
+
+	for _, attr := range doc.Attributes {
+		switch a := attr.(type) {
+		case *tg.DocumentAttributeAudio:
+			playable = true
+			media.Duration = int(a.Duration)
+			switch {
+			case a.Voice:
+				media.Title = "Voice Message"
+			case a.Title != "" && a.Performer != "":
+				media.Title = a.Performer + " - " + a.Title
+			case a.Title != "":
+				media.Title = a.Title
+			}
+		case *tg.DocumentAttributeVideo:
+			playable = true
+			media.Video = true
+			media.Duration = int(a.Duration)
+		case *tg.DocumentAttributeFilename:
+			media.fileName = a.FileName
+		}
+	}
+
+	// Files sent as documents carry no audio or video attributes
+	if strings.HasPrefix(doc.MimeType, "audio/") {
+		playable = true
+	}
+	if strings.HasPrefix(doc.MimeType, "video/") {
+		playable = true
+		media.Video = true
+	}
+	if !playable {
+		return nil
+	}
+
+	if media.Title == "" {
+		media.Title = strings.TrimSuffix(media.fileName, filepath.Ext(media.fileName))
+	}
+	if media.Title == "" {
+		media.Title = "Telegram Audio"
+		if media.Video {
+			media.Title = "Telegram Video"
+		}
+	}
+
+	return media
+}
+
+// Download saves the media into dir, reusing an earlier download of the same file
+// The file is written under a temporary name and only renamed once complete, so
+// an interrupted download is never mistaken for a finished one
+// onProgress receives the percentage downloaded every few seconds
+func (t *TGMedia) Download(ctx context.Context, dir string, onProgress func(percent float64)) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	ext := filepath.Ext(t.fileName)
+	if ext == "" {
+		ext = ".mp3"
+		if t.Video {
+			ext = ".mp4"
+		}
+	}
+	filePath := filepath.Join(dir, fmt.Sprintf("tg_%d%s", t.doc.ID, ext))
+
+	if _, err := os.Stat(filePath); err == nil {
+		return filePath, nil
+	}
+
+	part, err := os.CreateTemp(dir, fmt.Sprintf("tg_%d_*.part", t.doc.ID))
+	if err != nil {
+		return "", err
+	}
+	partPath := part.Name()
+	part.Close()
+
+	opts := &tg.DownloadOptions{
+		FileName: partPath,
+		Ctx:      ctx,
+	}
+	if onProgress != nil {
+		opts.ProgressCallback = func(info *tg.ProgressInfo) {
+			onProgress(info.Percentage)
+		}
+	}
+
+	path, err := t.Message.Download(opts)
+	if err != nil {
+		os.Remove(partPath)
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	if err := os.Rename(path, filePath); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return filePath, nil
+}
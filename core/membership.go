@@ -0,0 +1,196 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+// inviteLinkTTL is how long the one-time invite link of an assistant stays valid
+const inviteLinkTTL = 5 * time.Minute
+
+// membershipError tells the admins of a chat what to change so an assistant can get in
+type membershipError struct {
+	msg string
+}
+
+func (e *membershipError) Error() string { return e.msg }
+
+// SetInviter sets the bot that brings assistants into chats they are not part of
+// It needs the invite users right, and the ban users right to lift bans
+func (c *Calls) SetInviter(bot *tg.Client) {
+	c.assignmentsMu.Lock()
+	defer c.assignmentsMu.Unlock()
+	c.inviter = bot
+}
+
+// ensureMember gets an assistant into a chat before it joins the voice chat
+// The inviter lifts a ban of the assistant and invites it with a one-time link
+func (c *Calls) ensureMember(a *Assistant, chatID int64) error {
+	member, err := isMember(a, chatID)
+	if err != nil {
+		return fmt.Errorf("checking membership of %s failed: %w", assistantName(a), err)
+	}
+	if member {
+		return nil
+	}
+
+	c.assignmentsMu.RLock()
+	bot := c.inviter
+	c.assignmentsMu.RUnlock()
+	if bot == nil {
+		// Nobody can invite, the join reports what is wrong
+		return nil
+	}
+
+	name := assistantName(a)
+	log.Printf(">> %s is not in chat %d, inviting it", describeAssistant(a), chatID)
+
+	chat, err := bot.ResolvePeer(chatID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve peer (chatID: %d): %w", chatID, err)
+	}
+
+	if err := unbanAssistant(bot, chat, a); err != nil {
+		if tg.MatchError(err, "CHAT_ADMIN_REQUIRED") || tg.MatchError(err, "RIGHT_FORBIDDEN") {
+			return &membershipError{fmt.Sprintf(
+				"The assistant %s is banned in this chat. Unban it, or make me an admin with the \"Ban Users\" right.", name)}
+		}
+		return fmt.Errorf("unbanning %s failed: %w", name, err)
+	}
+
+	invite, err := bot.MessagesExportChatInvite(&tg.MessagesExportChatInviteParams{
+		Peer:       chat,
+		ExpireDate: int32(time.Now().Add(inviteLinkTTL).Unix()),
+		UsageLimit: 1,
+		Title:      "Assistant",
+	})
+	if err != nil {
+		if tg.MatchError(err, "CHAT_ADMIN_REQUIRED") || tg.MatchError(err, "RIGHT_FORBIDDEN") {
+			return &membershipError{fmt.Sprintf(
+				"The assistant %s is not in this chat. Make me an admin with the \"Invite Users via Link\" right, or add it manually.", name)}
+		}
+		return fmt.Errorf("exporting invite link failed: %w", err)
+	}
+	link, ok := invite.(*tg.ChatInviteExported)
+	if !ok {
+		return fmt.Errorf("unexpected invite type: %T", invite)
+	}
+
+	if err := joinByLink(a, link.Link); err != nil {
+		switch {
+		case tg.MatchError(err, "CHANNELS_TOO_MUCH"):
+			return &membershipError{fmt.Sprintf("The assistant %s is in too many chats to join this one.", name)}
+		case tg.MatchError(err, "USER_BANNED_IN_CHANNEL"), tg.MatchError(err, "CHANNEL_PRIVATE"):
+			return &membershipError{fmt.Sprintf(
+				"The assistant %s is banned in this chat. Unban it, or make me an admin with the \"Ban Users\" right.", name)}
+		}
+		return fmt.Errorf("%s failed to join the chat: %w", name, err)
+	}
+
+	log.Printf(">> %s joined chat %d", describeAssistant(a), chatID)
+	return nil
+}
+
+// isMember reports whether an assistant can see a chat as one of its members
+// Errors other than Telegram saying it is no member are returned as they are
+func isMember(a *Assistant, chatID int64) (bool, error) {
+	peer, err := a.Client.ResolvePeer(chatID)
+	if err != nil {
+		// A chat the assistant never was in has no access hash to resolve with
+		return false, nil
+	}
+	channel, ok := peer.(*tg.InputPeerChannel)
+	if !ok {
+		// Basic groups only resolve for their members
+		return true, nil
+	}
+
+	result, err := a.Client.ChannelsGetParticipant(&tg.InputChannelObj{
+		ChannelID:  channel.ChannelID,
+		AccessHash: channel.AccessHash,
+	}, &tg.InputPeerSelf{})
+	switch {
+	case tg.MatchError(err, "USER_NOT_PARTICIPANT"):
+		return false, nil
+	case tg.MatchError(err, "CHANNEL_PRIVATE"):
+		// What a kicked assistant is told, the invite lifts the ban
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	switch result.Participant.(type) {
+	case *tg.ChannelParticipantLeft, *tg.ChannelParticipantBanned:
+		return false, nil
+	}
+	return true, nil
+}
+
+// unbanAssistant lifts the ban of an assistant kicked from a supergroup
+// Assistants the bot can't look up are left for the invite to sort out
+func unbanAssistant(bot *tg.Client, chat tg.InputPeer, a *Assistant) error {
+	channel, ok := chat.(*tg.InputPeerChannel)
+	if !ok {
+		return nil
+	}
+
+	user, err := assistantPeer(bot, a)
+	if err != nil {
+		return nil
+	}
+	input := &tg.InputChannelObj{ChannelID: channel.ChannelID, AccessHash: channel.AccessHash}
+
+	result, err := bot.ChannelsGetParticipant(input, user)
+	if err != nil {
+		return nil
+	}
+	banned, ok := result.Participant.(*tg.ChannelParticipantBanned)
+	if !ok || banned.BannedRights == nil || !banned.BannedRights.ViewMessages {
+		return nil
+	}
+
+	log.Printf(">> %s is banned in chat %d, unbanning it", describeAssistant(a), bot.GetPeerID(chat))
+	_, err = bot.ChannelsEditBanned(input, user, &tg.ChatBannedRights{})
+	return err
+}
+
+// assistantPeer resolves an assistant as seen by the bot, by username when it has one
+func assistantPeer(bot *tg.Client, a *Assistant) (tg.InputPeer, error) {
+	if username := a.Client.Me().Username; username != "" {
+		return bot.ResolvePeer(username)
+	}
+	return bot.ResolvePeer(a.ID())
+}
+
+// joinByLink makes an assistant join a chat through an invite link
+func joinByLink(a *Assistant, link string) error {
+	_, err := a.Client.JoinChannel(link)
+	if err == nil || !tg.MatchError(err, "USER_ALREADY_PARTICIPANT") {
+		return err
+	}
+
+	// Already in but the chat isn't cached yet, the invite tells which chat it is
+	matches := tg.TgJoinRe.FindStringSubmatch(link)
+	if len(matches) < 2 {
+		return nil
+	}
+	invite, err := a.Client.MessagesCheckChatInvite(matches[1])
+	if err != nil {
+		return err
+	}
+	if already, ok := invite.(*tg.ChatInviteAlready); ok {
+		a.Client.Cache.UpdatePeersToCache(nil, []tg.Chat{already.Chat})
+	}
+	return nil
+}
+
+// assistantName returns the @username of an assistant, or its ID without one
+func assistantName(a *Assistant) string {
+	me := a.Client.Me()
+	if me.Username != "" {
+		return "@" + me.Username
+	}
+	return fmt.Sprintf("%d", me.ID)
+}
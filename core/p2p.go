@@ -0,0 +1,502 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	ntg "shizumusic/ntgcalls"
+)
+
+// privateCallTimeout is how long a private call may ring before it is given up
+const privateCallTimeout = 60 * time.Second
+
+// errCallDiscarded is returned when the other side ends a call during the key exchange
+var errCallDiscarded = errors.New("the call was declined or ended")
+
+// P2PConfig tracks the key exchange of a private call, keyed by the user on the other side
+type P2PConfig struct {
+	DhConfig       ntg.DhConfig
+	GAorB          []byte // g_a hash, g_a or g_b, whatever the other side sent last
+	KeyFingerprint int64
+	IsOutgoing     bool
+	PhoneCall      *tg.PhoneCallObj
+	WaitData       chan error
+
+	call      *tg.InputPhoneCall
+	video     bool
+	created   bool // ntgcalls holds a connection for the user
+	connected time.Time
+}
+
+// IncomingCallHandler decides what happens with a private call the assistant receives
+// It must answer the call with AnswerCall or reject it with DeclineCall
+type IncomingCallHandler func(userID int64)
+
+// PrivateCallEndHandler is called once a private call that was picked up has ended
+type PrivateCallEndHandler func(userID int64)
+
+// wait blocks until the other side moves the key exchange forward
+func (p *P2PConfig) wait() error {
+	select {
+	case err := <-p.WaitData:
+		return err
+	case <-time.After(privateCallTimeout):
+		return fmt.Errorf("no answer within %s", privateCallTimeout)
+	}
+}
+
+// notify wakes up a pending wait, extra updates are dropped
+func (p *P2PConfig) notify(err error) {
+	select {
+	case p.WaitData <- err:
+	default:
+	}
+}
+
+// EnablePrivateCalls lets the assistant place and receive private calls
+// Without a handler every incoming call is rejected as busy
+func (c *Calls) EnablePrivateCalls(onIncoming IncomingCallHandler) {
+	c.privateCallsMu.Lock()
+	c.incomingCall = onIncoming
+	c.privateCallsMu.Unlock()
+
+	// Signaling between both libraries is relayed through Telegram
+	c.primary().ntg.OnSignal(func(chatId int64, signal []byte) {
+		var call *tg.InputPhoneCall
+		c.privateCallsMu.Lock()
+		if config, ok := c.privateCalls[chatId]; ok {
+			call = config.call
+		}
+		c.privateCallsMu.Unlock()
+		if call == nil {
+			return
+		}
+		if _, err := c.primary().Client.PhoneSendSignalingData(call, signal); err != nil {
+			log.Printf(">> Sending signaling data failed for user %d: %v", chatId, err)
+		}
+	})
+
+	c.primary().Client.AddRawHandler(&tg.UpdatePhoneCallSignalingData{}, func(update tg.Update, _ *tg.Client) error {
+		upd, ok := update.(*tg.UpdatePhoneCallSignalingData)
+		if !ok {
+			return nil
+		}
+		if userID, _ := c.privateCallByID(upd.PhoneCallID); userID != 0 {
+			if err := c.primary().ntg.SendSignalingData(userID, upd.Data); err != nil {
+				log.Printf(">> Relaying signaling data failed for user %d: %v", userID, err)
+			}
+		}
+		return nil
+	})
+
+	c.primary().Client.AddRawHandler(&tg.UpdatePhoneCall{}, func(update tg.Update, _ *tg.Client) error {
+		if upd, ok := update.(*tg.UpdatePhoneCall); ok {
+			c.handlePhoneCall(upd.PhoneCall)
+		}
+		return nil
+	})
+}
+
+// OnPrivateCallEnd registers a handler for private calls that were picked up and ended
+func (c *Calls) OnPrivateCallEnd(handler PrivateCallEndHandler) {
+	c.privateCallsMu.Lock()
+	defer c.privateCallsMu.Unlock()
+	c.privateCallEndHandlers = append(c.privateCallEndHandlers, handler)
+}
+
+// CallUser rings a user from the assistant and plays a file once they pick up
+// Blocks until the call is connected, declined or times out
+func (c *Calls) CallUser(userID int64, filePath string, video bool) error {
+	config := &P2PConfig{IsOutgoing: true, WaitData: make(chan error, 1), video: video}
+	if !c.addPrivateCall(userID, config) {
+		return fmt.Errorf("already in a private call with user %d", userID)
+	}
+
+	if err := c.startPrivateCall(userID, config, filePath); err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+		return err
+	}
+
+	gAHash, err := c.primary().ntg.InitExchange(userID, config.DhConfig, nil)
+	if err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+		return fmt.Errorf("InitExchange failed: %w", err)
+	}
+
+	user, err := c.primary().Client.GetSendableUser(userID)
+	if err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+		return fmt.Errorf("resolving user %d failed: %w", userID, err)
+	}
+
+	log.Printf(">> Calling user %d - file: %s", userID, filePath)
+
+	requested, err := c.primary().Client.PhoneRequestCall(&tg.PhoneRequestCallParams{
+		Video:    video,
+		UserID:   user,
+		RandomID: rand.Int31(),
+		GAHash:   gAHash,
+		Protocol: callProtocol(),
+	})
+	if err != nil {
+		c.endPrivateCall(userID, config, nil)
+		return fmt.Errorf("PhoneRequestCall failed: %w", err)
+	}
+	if waiting, ok := requested.PhoneCall.(*tg.PhoneCallWaiting); ok {
+		c.setInputCall(config, waiting.ID, waiting.AccessHash)
+	}
+
+	// The user picks up with their g_b
+	if err := config.wait(); err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonMissed{})
+		return err
+	}
+
+	c.privateCallsMu.Lock()
+	gB, call := config.GAorB, config.call
+	c.privateCallsMu.Unlock()
+
+	auth, err := c.primary().ntg.ExchangeKeys(userID, gB, 0)
+	if err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+		return fmt.Errorf("ExchangeKeys failed: %w", err)
+	}
+
+	confirmed, err := c.primary().Client.PhoneConfirmCall(call, auth.GAOrB, auth.KeyFingerprint, callProtocol())
+	if err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+		return fmt.Errorf("PhoneConfirmCall failed: %w", err)
+	}
+
+	phoneCall, ok := confirmed.PhoneCall.(*tg.PhoneCallObj)
+	if !ok {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+		return fmt.Errorf("unexpected call state %T after confirming", confirmed.PhoneCall)
+	}
+	return c.connectPrivateCall(userID, config, phoneCall)
+}
+
+// AnswerCall picks up the ringing call of a user and plays a file to them
+func (c *Calls) AnswerCall(userID int64, filePath string, video bool) error {
+	c.privateCallsMu.Lock()
+	config := c.privateCalls[userID]
+	if config == nil || config.IsOutgoing || config.created {
+		c.privateCallsMu.Unlock()
+		return fmt.Errorf("no incoming call from user %d", userID)
+	}
+	gAHash, call := config.GAorB, config.call
+	config.video = video
+	c.privateCallsMu.Unlock()
+
+	if err := c.startPrivateCall(userID, config, filePath); err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+		return err
+	}
+
+	gB, err := c.primary().ntg.InitExchange(userID, config.DhConfig, gAHash)
+	if err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+		return fmt.Errorf("InitExchange failed: %w", err)
+	}
+
+	log.Printf(">> Answering call of user %d - file: %s", userID, filePath)
+
+	if _, err := c.primary().Client.PhoneAcceptCall(call, gB, callProtocol()); err != nil {
+		c.endPrivateCall(userID, config, nil)
+		return fmt.Errorf("PhoneAcceptCall failed: %w", err)
+	}
+
+	// The caller confirms with their g_a and the key fingerprint
+	if err := config.wait(); err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+		return err
+	}
+
+	c.privateCallsMu.Lock()
+	gA, fingerprint, phoneCall := config.GAorB, config.KeyFingerprint, config.PhoneCall
+	c.privateCallsMu.Unlock()
+
+	if _, err := c.primary().ntg.ExchangeKeys(userID, gA, fingerprint); err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+		return fmt.Errorf("ExchangeKeys failed: %w", err)
+	}
+	return c.connectPrivateCall(userID, config, phoneCall)
+}
+
+// DeclineCall rejects the ringing call of a user as busy
+func (c *Calls) DeclineCall(userID int64) error {
+	config := c.privateCall(userID)
+	if config == nil {
+		return fmt.Errorf("no call with user %d", userID)
+	}
+	c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonBusy{})
+	return nil
+}
+
+// HangUp ends the private call with a user
+func (c *Calls) HangUp(userID int64) error {
+	config := c.privateCall(userID)
+	if config == nil {
+		return fmt.Errorf("no call with user %d", userID)
+	}
+	c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonHangup{})
+	return nil
+}
+
+// IsPrivateCall reports whether the assistant is ringing or talking to a user
+func (c *Calls) IsPrivateCall(userID int64) bool {
+	return c.privateCall(userID) != nil
+}
+
+// startPrivateCall fetches fresh DH parameters and prepares the ntgcalls side of a call
+func (c *Calls) startPrivateCall(userID int64, config *P2PConfig, filePath string) error {
+	dh, err := c.dhConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := c.primary().ntg.CreateP2PCall(userID); err != nil {
+		return fmt.Errorf("CreateP2PCall failed: %w", err)
+	}
+
+	c.privateCallsMu.Lock()
+	config.DhConfig = dh
+	config.created = true
+	video := config.video
+	c.privateCallsMu.Unlock()
+
+	media := mediaDescription(filePath, video, 0, AudioEffects{}, c.Quality(userID))
+	if err := c.primary().ntg.SetStreamSources(userID, ntg.CaptureStream, media); err != nil {
+		return fmt.Errorf("SetStreamSources failed: %w", err)
+	}
+	return nil
+}
+
+// connectPrivateCall opens the WebRTC connection of an accepted and keyed call
+func (c *Calls) connectPrivateCall(userID int64, config *P2PConfig, call *tg.PhoneCallObj) error {
+	var versions []string
+	if call.Protocol != nil {
+		versions = call.Protocol.LibraryVersions
+	}
+
+	if err := c.primary().ntg.ConnectP2P(userID, rtcServers(call.Connections), versions, call.P2PAllowed); err != nil {
+		c.endPrivateCall(userID, config, &tg.PhoneCallDiscardReasonDisconnect{})
+		return fmt.Errorf("ConnectP2P failed: %w", err)
+	}
+
+	c.privateCallsMu.Lock()
+	config.PhoneCall = call
+	config.connected = time.Now()
+	c.privateCallsMu.Unlock()
+
+	log.Printf(">> ✅ Private call connected with user %d", userID)
+	return nil
+}
+
+// handlePhoneCall moves the calls of the assistant along as their state changes
+func (c *Calls) handlePhoneCall(update tg.PhoneCall) {
+	switch call := update.(type) {
+	case *tg.PhoneCallRequested:
+		c.handleIncomingCall(call)
+
+	case *tg.PhoneCallAccepted:
+		c.privateCallsMu.Lock()
+		config := c.privateCalls[call.ParticipantID]
+		if config != nil && config.IsOutgoing {
+			config.GAorB = call.GB
+			config.call = &tg.InputPhoneCall{ID: call.ID, AccessHash: call.AccessHash}
+		}
+		c.privateCallsMu.Unlock()
+		if config != nil && config.IsOutgoing {
+			config.notify(nil)
+		}
+
+	case *tg.PhoneCallObj:
+		c.privateCallsMu.Lock()
+		config := c.privateCalls[call.AdminID]
+		if config != nil && !config.IsOutgoing && config.PhoneCall == nil {
+			config.GAorB = call.GAOrB
+			config.KeyFingerprint = call.KeyFingerprint
+			config.PhoneCall = call
+		} else {
+			config = nil
+		}
+		c.privateCallsMu.Unlock()
+		if config != nil {
+			config.notify(nil)
+		}
+
+	case *tg.PhoneCallDiscarded:
+		userID, config := c.privateCallByID(call.ID)
+		if config == nil {
+			return
+		}
+		log.Printf(">> Private call with user %d was ended by them", userID)
+		c.endPrivateCall(userID, config, nil)
+		config.notify(errCallDiscarded)
+	}
+}
+
+// handleIncomingCall registers a ringing call and hands it to the incoming call handler
+func (c *Calls) handleIncomingCall(call *tg.PhoneCallRequested) {
+	inputCall := &tg.InputPhoneCall{ID: call.ID, AccessHash: call.AccessHash}
+	config := &P2PConfig{
+		GAorB:    call.GAHash,
+		WaitData: make(chan error, 1),
+		call:     inputCall,
+		video:    call.Video,
+	}
+
+	c.privateCallsMu.Lock()
+	handler := c.incomingCall
+	c.privateCallsMu.Unlock()
+
+	if handler == nil || !c.addPrivateCall(call.AdminID, config) {
+		c.primary().Client.PhoneDiscardCall(&tg.PhoneDiscardCallParams{
+			Peer:   inputCall,
+			Reason: &tg.PhoneCallDiscardReasonBusy{},
+		})
+		return
+	}
+
+	log.Printf(">> Incoming private call from user %d", call.AdminID)
+	c.primary().Client.PhoneReceivedCall(inputCall)
+	go handler(call.AdminID)
+}
+
+// endPrivateCall forgets a call, closes its connection and discards it on Telegram
+// A nil reason skips the discard, for calls the other side already ended
+func (c *Calls) endPrivateCall(userID int64, config *P2PConfig, reason tg.PhoneCallDiscardReason) {
+	c.privateCallsMu.Lock()
+	if c.privateCalls[userID] != config {
+		c.privateCallsMu.Unlock()
+		return
+	}
+	delete(c.privateCalls, userID)
+	call, created, connected, video := config.call, config.created, config.connected, config.video
+	c.privateCallsMu.Unlock()
+
+	if created {
+		c.primary().ntg.Stop(userID)
+	}
+	if !connected.IsZero() {
+		c.privateCallsMu.Lock()
+		handlers := append([]PrivateCallEndHandler{}, c.privateCallEndHandlers...)
+		c.privateCallsMu.Unlock()
+		for _, handler := range handlers {
+			handler(userID)
+		}
+	}
+	if reason == nil || call == nil {
+		return
+	}
+
+	var duration int32
+	if !connected.IsZero() {
+		duration = int32(time.Since(connected).Seconds())
+	}
+	if _, err := c.primary().Client.PhoneDiscardCall(&tg.PhoneDiscardCallParams{
+		Video:    video,
+		Peer:     call,
+		Duration: duration,
+		Reason:   reason,
+	}); err != nil {
+		log.Printf(">> Discarding call with user %d failed: %v", userID, err)
+	}
+}
+
+// addPrivateCall registers a call, false when one with the user already runs
+func (c *Calls) addPrivateCall(userID int64, config *P2PConfig) bool {
+	c.privateCallsMu.Lock()
+	defer c.privateCallsMu.Unlock()
+	if _, busy := c.privateCalls[userID]; busy {
+		return false
+	}
+	c.privateCalls[userID] = config
+	return true
+}
+
+func (c *Calls) privateCall(userID int64) *P2PConfig {
+	c.privateCallsMu.Lock()
+	defer c.privateCallsMu.Unlock()
+	return c.privateCalls[userID]
+}
+
+// privateCallByID finds a call by its Telegram call ID
+func (c *Calls) privateCallByID(callID int64) (int64, *P2PConfig) {
+	c.privateCallsMu.Lock()
+	defer c.privateCallsMu.Unlock()
+	for userID, config := range c.privateCalls {
+		if config.call != nil && config.call.ID == callID {
+			return userID, config
+		}
+	}
+	return 0, nil
+}
+
+func (c *Calls) setInputCall(config *P2PConfig, id, accessHash int64) {
+	c.privateCallsMu.Lock()
+	defer c.privateCallsMu.Unlock()
+	if config.call == nil {
+		config.call = &tg.InputPhoneCall{ID: id, AccessHash: accessHash}
+	}
+}
+
+// dhConfig fetches the Diffie-Hellman parameters, with fresh random bytes, for a key exchange
+func (c *Calls) dhConfig() (ntg.DhConfig, error) {
+	result, err := c.primary().Client.MessagesGetDhConfig(0, 256)
+	if err != nil {
+		return ntg.DhConfig{}, fmt.Errorf("MessagesGetDhConfig failed: %w", err)
+	}
+	dh, ok := result.(*tg.MessagesDhConfigObj)
+	if !ok {
+		return ntg.DhConfig{}, fmt.Errorf("unexpected DH config %T", result)
+	}
+	return ntg.DhConfig{G: dh.G, P: dh.P, Random: dh.Random}, nil
+}
+
+// callProtocol describes the call protocol ntgcalls supports
+func callProtocol() *tg.PhoneCallProtocol {
+	protocol := ntg.GetProtocol()
+	return &tg.PhoneCallProtocol{
+		UdpP2P:          protocol.UdpP2P,
+		UdpReflector:    protocol.UdpReflector,
+		MinLayer:        protocol.MinLayer,
+		MaxLayer:        protocol.MaxLayer,
+		LibraryVersions: protocol.Versions,
+	}
+}
+
+// rtcServers converts the endpoints Telegram offers for a call
+func rtcServers(connections []tg.PhoneConnection) []ntg.RTCServer {
+	servers := make([]ntg.RTCServer, 0, len(connections))
+	for _, conn := range connections {
+		switch server := conn.(type) {
+		case *tg.PhoneConnectionWebrtc:
+			servers = append(servers, ntg.RTCServer{
+				ID:       server.ID,
+				Ipv4:     server.Ip,
+				Ipv6:     server.Ipv6,
+				Username: server.Username,
+				Password: server.Password,
+				Port:     server.Port,
+				Turn:     server.Turn,
+				Stun:     server.Stun,
+			})
+		case *tg.PhoneConnectionObj:
+			servers = append(servers, ntg.RTCServer{
+				ID:      server.ID,
+				Ipv4:    server.Ip,
+				Ipv6:    server.Ipv6,
+				Port:    server.Port,
+				Tcp:     server.Tcp,
+				PeerTag: server.PeerTag,
+			})
+		}
+	}
+	return servers
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"fmt"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+)
+
+// listenerPageSize is how many participants are inspected per listener count
+// Anyone past the first page is assumed to be a human listener
+const listenerPageSize = 100
+
+// ListenerCount fetches how many humans are listening in the voice chat of a chat
+// The assistant and other bots don't count, the result is cached for Listeners
+func (c *Calls) ListenerCount(chatID int64) (int, error) {
+	groupCall, err := c.sessionGroupCall(chatID)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := c.assistant(chatID).Client.PhoneGetGroupParticipants(groupCall, []tg.InputPeer{}, []int32{}, "", listenerPageSize)
+	if err != nil {
+		return 0, fmt.Errorf("PhoneGetGroupParticipants failed: %w", err)
+	}
+
+	bots := make(map[int64]bool)
+	for _, u := range result.Users {
+		if user, ok := u.(*tg.UserObj); ok && user.Bot {
+			bots[user.ID] = true
+		}
+	}
+
+	listeners := int(result.Count) - len(result.Participants)
+	for _, participant := range result.Participants {
+		if participant.Self || participant.Left {
+			continue
+		}
+		if peer, ok := participant.Peer.(*tg.PeerUser); ok && bots[peer.UserID] {
+			continue
+		}
+		listeners++
+	}
+	if listeners < 0 {
+		listeners = 0
+	}
+
+	c.listenersMu.Lock()
+	c.listeners[chatID] = listeners
+	c.listenersMu.Unlock()
+
+	return listeners, nil
+}
+
+// Listeners returns the listener count of a chat from the last ListenerCount call
+// ok is false while the chat wasn't counted yet
+func (c *Calls) Listeners(chatID int64) (count int, ok bool) {
+	c.listenersMu.RLock()
+	defer c.listenersMu.RUnlock()
+	count, ok = c.listeners[chatID]
+	return count, ok
+}
+
+func (c *Calls) forgetListeners(chatID int64) {
+	c.listenersMu.Lock()
+	defer c.listenersMu.Unlock()
+	delete(c.listeners, chatID)
+}
+
+// sessionGroupCall returns the group call joined in a chat
+// Sessions remember it, so polling doesn't resolve the chat again
+func (c *Calls) sessionGroupCall(chatID int64) (*tg.InputGroupCallObj, error) {
+	c.activeSessionsMu.RLock()
+	s, ok := c.activeSessions[chatID]
+	c.activeSessionsMu.RUnlock()
+	if ok && s.groupCall != nil {
+		return s.groupCall, nil
+	}
+	return c.GetInputGroupCall(chatID)
+}
@@ -0,0 +1,291 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PlayerState is where the player of a chat is in its lifecycle
+// idle → joining → playing ⇄ paused → ending → idle
+type PlayerState int
+
+const (
+	StateIdle PlayerState = iota
+	StateJoining
+	StatePlaying
+	StatePaused
+	StateEnding
+)
+
+func (s PlayerState) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateJoining:
+		return "joining"
+	case StatePlaying:
+		return "playing"
+	case StatePaused:
+		return "paused"
+	case StateEnding:
+		return "ending"
+	}
+	return fmt.Sprintf("state(%d)", int(s))
+}
+
+// Active reports whether the assistant streams in the chat, paused or not
+func (s PlayerState) Active() bool {
+	return s == StatePlaying || s == StatePaused
+}
+
+// PlayerEvent moves the player of a chat from one state to the next
+type PlayerEvent int
+
+const (
+	EventJoin       PlayerEvent = iota // a join was started
+	EventJoined                        // the stream is running
+	EventJoinFailed                    // no assistant could join
+	EventPause
+	EventResume
+	EventEnd   // leaving was started
+	EventEnded // the call is torn down
+)
+
+func (e PlayerEvent) String() string {
+	switch e {
+	case EventJoin:
+		return "join"
+	case EventJoined:
+		return "finish joining"
+	case EventJoinFailed:
+		return "fail joining"
+	case EventPause:
+		return "pause"
+	case EventResume:
+		return "resume"
+	case EventEnd:
+		return "end"
+	case EventEnded:
+		return "finish ending"
+	}
+	return fmt.Sprintf("event(%d)", int(e))
+}
+
+// playerTransitions lists every allowed transition, anything else is rejected
+// Pausing a paused and resuming a playing chat are accepted and change nothing
+var playerTransitions = map[PlayerState]map[PlayerEvent]PlayerState{
+	StateIdle: {
+		EventJoin: StateJoining,
+	},
+	StateJoining: {
+		EventJoined:     StatePlaying,
+		EventJoinFailed: StateIdle,
+	},
+	StatePlaying: {
+		EventPause:  StatePaused,
+		EventResume: StatePlaying,
+		EventEnd:    StateEnding,
+	},
+	StatePaused: {
+		EventPause:  StatePaused,
+		EventResume: StatePlaying,
+		EventEnd:    StateEnding,
+	},
+	StateEnding: {
+		EventEnded: StateIdle,
+	},
+}
+
+// transitionError is returned for an event the current state doesn't accept
+type transitionError struct {
+	chatID int64
+	state  PlayerState
+	event  PlayerEvent
+}
+
+func (e *transitionError) Error() string {
+	return fmt.Sprintf("chat %d can't %s while %s", e.chatID, e.event, e.state)
+}
+
+// ChatState is a snapshot of the player of a chat
+type ChatState struct {
+	ChatID   int64
+	State    PlayerState
+	Since    time.Time // when State was entered
+	JoinTime time.Time // when the stream started, zero until joined
+	VCType   string    // "voice" or "video"
+}
+
+// PlayerStateHandler is called after the player of a chat changed its state
+// It runs on the owner goroutine of the chat and must not fire events itself
+type PlayerStateHandler func(chatID int64, from, to PlayerState)
+
+// PlayerStates holds the state machine of every chat
+// Each chat that left idle has one goroutine owning its state,
+// events are handed to it and everyone else only reads snapshots
+type PlayerStates struct {
+	machines map[int64]*playerMachine
+	mu       sync.Mutex
+
+	snapshots   map[int64]ChatState
+	snapshotsMu sync.RWMutex
+
+	handlers   []PlayerStateHandler
+	handlersMu sync.RWMutex
+}
+
+// playerMachine is the inbox of the owner goroutine of a chat
+type playerMachine struct {
+	events  chan playerTransition
+	pending int // events handed out but not received yet, guarded by PlayerStates.mu
+}
+
+type playerTransition struct {
+	event  PlayerEvent
+	vcType string // set with EventJoin
+	done   chan transitionResult
+}
+
+type transitionResult struct {
+	from PlayerState
+	err  error
+}
+
+func newPlayerStates() *PlayerStates {
+	return &PlayerStates{
+		machines:  make(map[int64]*playerMachine),
+		snapshots: make(map[int64]ChatState),
+	}
+}
+
+// OnChange registers a handler for state changes of any chat
+func (p *PlayerStates) OnChange(handler PlayerStateHandler) {
+	p.handlersMu.Lock()
+	defer p.handlersMu.Unlock()
+	p.handlers = append(p.handlers, handler)
+}
+
+// State returns the snapshot of a chat, idle ones included
+func (p *PlayerStates) State(chatID int64) ChatState {
+	p.snapshotsMu.RLock()
+	defer p.snapshotsMu.RUnlock()
+	if state, ok := p.snapshots[chatID]; ok {
+		return state
+	}
+	return ChatState{ChatID: chatID, State: StateIdle}
+}
+
+// Active returns the snapshots of every chat that is playing or paused, oldest join first
+func (p *PlayerStates) Active() []ChatState {
+	p.snapshotsMu.RLock()
+	states := make([]ChatState, 0, len(p.snapshots))
+	for _, state := range p.snapshots {
+		if state.State.Active() {
+			states = append(states, state)
+		}
+	}
+	p.snapshotsMu.RUnlock()
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].JoinTime.Before(states[j].JoinTime)
+	})
+	return states
+}
+
+// fire hands an event to the owner of a chat and waits until it was applied
+// It returns the state the event found the chat in
+func (p *PlayerStates) fire(chatID int64, event PlayerEvent, vcType string) (PlayerState, error) {
+	p.mu.Lock()
+	m, ok := p.machines[chatID]
+	if !ok {
+		m = &playerMachine{events: make(chan playerTransition)}
+		p.machines[chatID] = m
+		go p.own(chatID, m)
+	}
+	m.pending++
+	p.mu.Unlock()
+
+	t := playerTransition{event: event, vcType: vcType, done: make(chan transitionResult, 1)}
+	m.events <- t
+	result := <-t.done
+	return result.from, result.err
+}
+
+// own is the owner goroutine of a chat, the only one changing its state
+// It exits once the chat is back to idle and nobody is about to send to it
+func (p *PlayerStates) own(chatID int64, m *playerMachine) {
+	state := ChatState{ChatID: chatID, State: StateIdle, Since: time.Now()}
+	for t := range m.events {
+		p.mu.Lock()
+		m.pending--
+		p.mu.Unlock()
+
+		from := state.State
+		result := transitionResult{from: from}
+		next, ok := playerTransitions[from][t.event]
+		switch {
+		case !ok:
+			result.err = &transitionError{chatID: chatID, state: from, event: t.event}
+		case next != from:
+			state = advance(state, t, next)
+			p.publish(state)
+			p.notify(chatID, from, next)
+		}
+
+		// The owner is gone before the sender hears back
+		done := state.State == StateIdle && p.release(chatID, m)
+		t.done <- result
+		if done {
+			return
+		}
+	}
+}
+
+// release unregisters the owner of an idle chat unless more events are on their way
+func (p *PlayerStates) release(chatID int64, m *playerMachine) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if m.pending > 0 {
+		return false
+	}
+	delete(p.machines, chatID)
+	return true
+}
+
+// advance returns the snapshot after entering next
+func advance(state ChatState, t playerTransition, next PlayerState) ChatState {
+	now := time.Now()
+	switch {
+	case next == StateIdle:
+		return ChatState{ChatID: state.ChatID, State: StateIdle, Since: now}
+	case t.event == EventJoin:
+		state.VCType = t.vcType
+	case t.event == EventJoined:
+		state.JoinTime = now
+	}
+	state.State = next
+	state.Since = now
+	return state
+}
+
+func (p *PlayerStates) publish(state ChatState) {
+	p.snapshotsMu.Lock()
+	defer p.snapshotsMu.Unlock()
+	if state.State == StateIdle {
+		delete(p.snapshots, state.ChatID)
+		return
+	}
+	p.snapshots[state.ChatID] = state
+}
+
+func (p *PlayerStates) notify(chatID int64, from, to PlayerState) {
+	p.handlersMu.RLock()
+	handlers := append([]PlayerStateHandler{}, p.handlers...)
+	p.handlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(chatID, from, to)
+	}
+}
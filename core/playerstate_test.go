@@ -0,0 +1,185 @@
+package core
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// step is one event fired at a chat and what it must lead to
+type step struct {
+	event    PlayerEvent
+	from     PlayerState // state the event must find the chat in
+	want     PlayerState // state the chat must be in afterwards
+	rejected bool
+}
+
+// play fires every step at a fresh chat through its owner goroutine
+func play(t *testing.T, steps []step) {
+	t.Helper()
+	p := newPlayerStates()
+	const chatID = 1
+
+	for i, s := range steps {
+		from, err := p.fire(chatID, s.event, "voice")
+		if from != s.from {
+			t.Errorf("step %d: %s found the chat %s, want %s", i, s.event, from, s.from)
+		}
+
+		var terr *transitionError
+		switch {
+		case s.rejected && !errors.As(err, &terr):
+			t.Errorf("step %d: %s while %s was accepted, want a transition error", i, s.event, from)
+		case !s.rejected && err != nil:
+			t.Errorf("step %d: %s while %s: %v", i, s.event, from, err)
+		}
+
+		if got := p.State(chatID).State; got != s.want {
+			t.Fatalf("step %d: after %s the chat is %s, want %s", i, s.event, got, s.want)
+		}
+		if active := len(p.Active()) == 1; active != s.want.Active() {
+			t.Errorf("step %d: chat listed as active %v while %s", i, active, s.want)
+		}
+	}
+}
+
+func TestPlayerLifecycle(t *testing.T) {
+	play(t, []step{
+		{event: EventJoin, from: StateIdle, want: StateJoining},
+		{event: EventJoined, from: StateJoining, want: StatePlaying},
+		{event: EventPause, from: StatePlaying, want: StatePaused},
+		{event: EventResume, from: StatePaused, want: StatePlaying},
+		{event: EventEnd, from: StatePlaying, want: StateEnding},
+		{event: EventEnded, from: StateEnding, want: StateIdle},
+		// The idle chat can be joined again
+		{event: EventJoin, from: StateIdle, want: StateJoining},
+	})
+}
+
+func TestPlayerJoinFailed(t *testing.T) {
+	play(t, []step{
+		{event: EventJoin, from: StateIdle, want: StateJoining},
+		{event: EventJoinFailed, from: StateJoining, want: StateIdle},
+		{event: EventJoin, from: StateIdle, want: StateJoining},
+		{event: EventJoined, from: StateJoining, want: StatePlaying},
+	})
+}
+
+func TestPlayerEndWhilePaused(t *testing.T) {
+	play(t, []step{
+		{event: EventJoin, from: StateIdle, want: StateJoining},
+		{event: EventJoined, from: StateJoining, want: StatePlaying},
+		{event: EventPause, from: StatePlaying, want: StatePaused},
+		{event: EventPause, from: StatePaused, want: StatePaused},
+		{event: EventEnd, from: StatePaused, want: StateEnding},
+		{event: EventEnded, from: StateEnding, want: StateIdle},
+	})
+}
+
+func TestPlayerRejectsEventsOutOfOrder(t *testing.T) {
+	play(t, []step{
+		{event: EventJoined, from: StateIdle, want: StateIdle, rejected: true},
+		{event: EventPause, from: StateIdle, want: StateIdle, rejected: true},
+		{event: EventEnded, from: StateIdle, want: StateIdle, rejected: true},
+		{event: EventJoin, from: StateIdle, want: StateJoining},
+		{event: EventJoin, from: StateJoining, want: StateJoining, rejected: true},
+		{event: EventPause, from: StateJoining, want: StateJoining, rejected: true},
+		{event: EventEnd, from: StateJoining, want: StateJoining, rejected: true},
+		{event: EventJoined, from: StateJoining, want: StatePlaying},
+		{event: EventJoin, from: StatePlaying, want: StatePlaying, rejected: true},
+		{event: EventJoinFailed, from: StatePlaying, want: StatePlaying, rejected: true},
+		{event: EventEnded, from: StatePlaying, want: StatePlaying, rejected: true},
+		{event: EventEnd, from: StatePlaying, want: StateEnding},
+		{event: EventResume, from: StateEnding, want: StateEnding, rejected: true},
+		{event: EventEnd, from: StateEnding, want: StateEnding, rejected: true},
+		{event: EventEnded, from: StateEnding, want: StateIdle},
+	})
+}
+
+func TestPlayerConcurrentEvents(t *testing.T) {
+	p := newPlayerStates()
+	const chats = 8
+
+	var wg sync.WaitGroup
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		wg.Add(1)
+		go func(chatID int64) {
+			defer wg.Done()
+			p.fire(chatID, EventJoin, "voice")
+			p.fire(chatID, EventJoined, "")
+
+			// Pauses and resumes race each other, every one must be accepted
+			var toggles sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				toggles.Add(1)
+				go func(i int) {
+					defer toggles.Done()
+					event := EventPause
+					if i%2 == 1 {
+						event = EventResume
+					}
+					if _, err := p.fire(chatID, event, ""); err != nil {
+						t.Errorf("chat %d: %v", chatID, err)
+					}
+				}(i)
+			}
+			toggles.Wait()
+
+			if state := p.State(chatID).State; !state.Active() {
+				t.Errorf("chat %d ended up %s after pausing and resuming", chatID, state)
+			}
+			p.fire(chatID, EventEnd, "")
+			p.fire(chatID, EventEnded, "")
+		}(chatID)
+	}
+	wg.Wait()
+
+	if len(p.Active()) != 0 {
+		t.Errorf("ended chats are still active")
+	}
+}
+
+func TestPlayerSnapshots(t *testing.T) {
+	p := newPlayerStates()
+
+	var changes []PlayerState
+	p.OnChange(func(chatID int64, from, to PlayerState) {
+		changes = append(changes, to)
+	})
+
+	p.fire(1, EventJoin, "voice")
+	p.fire(1, EventJoined, "")
+	p.fire(1, EventPause, "")
+	state := p.State(1)
+	if state.VCType != "voice" || state.JoinTime.IsZero() {
+		t.Errorf("paused snapshot lost its join: %+v", state)
+	}
+	if active := p.Active(); len(active) != 1 || active[0].ChatID != 1 {
+		t.Errorf("want chat 1 active, got %+v", active)
+	}
+
+	// Pausing again changes nothing and notifies nobody
+	p.fire(1, EventPause, "")
+	p.fire(1, EventEnd, "")
+	p.fire(1, EventEnded, "")
+
+	want := []PlayerState{StateJoining, StatePlaying, StatePaused, StateEnding, StateIdle}
+	if len(changes) != len(want) {
+		t.Fatalf("want changes %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("want changes %v, got %v", want, changes)
+		}
+	}
+
+	if len(p.Active()) != 0 {
+		t.Errorf("ended chat is still active")
+	}
+	p.mu.Lock()
+	owners := len(p.machines)
+	p.mu.Unlock()
+	if owners != 0 {
+		t.Errorf("owner goroutine of an idle chat is still registered")
+	}
+}
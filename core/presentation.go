@@ -0,0 +1,169 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	ntg "shizumusic/ntgcalls"
+)
+
+// PresentationEndHandler is called with the shared file once a presentation is over,
+// stopped, finished or left with its call
+type PresentationEndHandler func(chatID int64, filePath string)
+
+// OnPresentationEnd registers a handler for presentations that are over
+func (c *Calls) OnPresentationEnd(handler PresentationEndHandler) {
+	c.streamEndHandlersMu.Lock()
+	defer c.streamEndHandlersMu.Unlock()
+	c.presentationEndHandlers = append(c.presentationEndHandlers, handler)
+}
+
+func (c *Calls) presentationEnded(chatID int64, filePath string) {
+	c.streamEndHandlersMu.RLock()
+	handlers := append([]PresentationEndHandler{}, c.presentationEndHandlers...)
+	c.streamEndHandlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(chatID, filePath)
+	}
+}
+
+// StartPresentation shares a video as the presentation stream of a joined call
+// Clients see it next to the camera stream, it plays independently of the queue
+// The stream sources must be rebuilt afterwards for the video to start
+func (c *Calls) StartPresentation(chatID int64, filePath string) error {
+	if !c.IsActive(chatID) {
+		return fmt.Errorf("no active stream in chat %d", chatID)
+	}
+
+	if c.Presentation(chatID) == "" {
+		if err := c.joinPresentation(chatID); err != nil {
+			return err
+		}
+	}
+
+	c.updateSession(chatID, func(s *VCSession) {
+		s.Presentation = filePath
+		s.presentationStart = time.Now()
+	})
+	return nil
+}
+
+// StopPresentation ends the presentation stream of a chat, the main stream keeps playing
+func (c *Calls) StopPresentation(chatID int64) error {
+	filePath := ""
+	c.updateSession(chatID, func(s *VCSession) {
+		filePath = s.Presentation
+		s.Presentation = ""
+	})
+	if filePath == "" {
+		return fmt.Errorf("no presentation in chat %d", chatID)
+	}
+
+	a := c.assistant(chatID)
+	if err := a.ntg.StopPresentation(chatID); err != nil {
+		log.Printf(">> Stopping presentation failed for chat %d: %v", chatID, err)
+	}
+	if groupCall, err := c.sessionGroupCall(chatID); err == nil {
+		a.Client.PhoneLeaveGroupCallPresentation(groupCall)
+	}
+	c.presentationEnded(chatID, filePath)
+	return nil
+}
+
+// Presentation returns the video shared as presentation in a chat, "" when none
+func (c *Calls) Presentation(chatID int64) string {
+	c.activeSessionsMu.RLock()
+	defer c.activeSessionsMu.RUnlock()
+	if s, ok := c.activeSessions[chatID]; ok {
+		return s.Presentation
+	}
+	return ""
+}
+
+// joinPresentation opens the second WebRTC connection a presentation streams over
+func (c *Calls) joinPresentation(chatID int64) error {
+	a := c.assistant(chatID)
+	offer, err := a.ntg.InitPresentation(chatID)
+	if err != nil {
+		return fmt.Errorf("InitPresentation failed: %w", err)
+	}
+
+	groupCall, err := c.sessionGroupCall(chatID)
+	if err != nil {
+		a.ntg.StopPresentation(chatID)
+		return err
+	}
+
+	result, err := a.Client.PhoneJoinGroupCallPresentation(groupCall, &tg.DataJson{Data: offer})
+	if err != nil {
+		a.ntg.StopPresentation(chatID)
+		return fmt.Errorf("PhoneJoinGroupCallPresentation failed: %w", err)
+	}
+
+	answer := transportAnswer(result, true)
+	if answer == "" {
+		a.ntg.StopPresentation(chatID)
+		return fmt.Errorf("presentation transport params missing from Telegram response")
+	}
+
+	if err := a.ntg.Connect(chatID, answer, true); err != nil {
+		a.ntg.StopPresentation(chatID)
+		a.Client.PhoneLeaveGroupCallPresentation(groupCall)
+		return fmt.Errorf("Connect failed: %w", err)
+	}
+
+	log.Printf(">> Presentation connected for chat %d", chatID)
+	return nil
+}
+
+// withPresentation adds the running presentation of a chat to its stream sources
+// The presentation continues where it is instead of restarting with the main track
+func (c *Calls) withPresentation(chatID int64, media ntg.MediaDescription) ntg.MediaDescription {
+	c.activeSessionsMu.RLock()
+	s, ok := c.activeSessions[chatID]
+	var filePath string
+	var started time.Time
+	if ok {
+		filePath, started = s.Presentation, s.presentationStart
+	}
+	c.activeSessionsMu.RUnlock()
+
+	if filePath == "" {
+		return media
+	}
+
+	quality := c.Quality(chatID)
+	offset := int(time.Since(started).Seconds())
+
+	source, input := ntg.MediaSourceFFmpeg, filePath
+	if offset > 0 || quality.Bitrate > 0 {
+		source = ntg.MediaSourceShell
+		input = ffmpegVideoCommand(filePath, offset, AudioEffects{}, quality)
+	}
+
+	media.Screen = &ntg.VideoDescription{
+		MediaSource: source,
+		Input:       input,
+		Width:       int16(quality.Width),
+		Height:      int16(quality.Height),
+		Fps:         uint8(quality.Fps),
+	}
+	return media
+}
+
+// transportAnswer extracts the WebRTC answer of a join from its updates
+func transportAnswer(result tg.Updates, presentation bool) string {
+	updates, ok := result.(*tg.UpdatesObj)
+	if !ok {
+		return ""
+	}
+	for _, upd := range updates.Updates {
+		if conn, ok := upd.(*tg.UpdateGroupCallConnection); ok && conn.Presentation == presentation {
+			return conn.Params.Data
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StreamQuality is the audio and video format a chat streams in
+type StreamQuality struct {
+	Preset     string `bson:"preset"`
+	SampleRate int    `bson:"sample_rate"`
+	Channels   int    `bson:"channels"`
+	Width      int    `bson:"width"`
+	Height     int    `bson:"height"`
+	Fps        int    `bson:"fps"`
+	Bitrate    int    `bson:"bitrate"` // video kbps, 0 leaves the source untouched
+}
+
+// Quality preset names, from the lightest to the heaviest
+const (
+	QualityLow    = "low"
+	QualityMedium = "medium"
+	QualityHigh   = "high"
+	QualityStudio = "studio"
+	QualityCustom = "custom"
+)
+
+// QualityPresets lists the preset names in the order they are offered
+var QualityPresets = []string{QualityLow, QualityMedium, QualityHigh, QualityStudio}
+
+var qualityPresets = map[string]StreamQuality{
+	QualityLow:    {Preset: QualityLow, SampleRate: 24000, Channels: 1, Width: 640, Height: 360, Fps: 20},
+	QualityMedium: {Preset: QualityMedium, SampleRate: 48000, Channels: 1, Width: 854, Height: 480, Fps: 24},
+	QualityHigh:   {Preset: QualityHigh, SampleRate: 48000, Channels: 2, Width: 1280, Height: 720, Fps: 24},
+	QualityStudio: {Preset: QualityStudio, SampleRate: 48000, Channels: 2, Width: 1920, Height: 1080, Fps: 30},
+}
+
+// Limits accepted for custom video profiles
+const (
+	minVideoSide   = 144
+	maxVideoWidth  = 1920
+	maxVideoHeight = 1080
+	maxVideoFps    = 60
+	minBitrate     = 100
+	maxBitrate     = 10000
+)
+
+// DefaultQuality returns the profile used by chats that never picked one
+func DefaultQuality() StreamQuality {
+	return qualityPresets[QualityHigh]
+}
+
+// QualityPreset returns a preset profile by name
+func QualityPreset(name string) (StreamQuality, bool) {
+	quality, ok := qualityPresets[strings.ToLower(name)]
+	return quality, ok
+}
+
+// CustomQuality builds a custom video profile on top of the audio format of base
+// A bitrate of 0 leaves the video as the source has it
+func CustomQuality(base StreamQuality, width, height, fps, bitrate int) (StreamQuality, error) {
+	if width < minVideoSide || width > maxVideoWidth || height < minVideoSide || height > maxVideoHeight {
+		return StreamQuality{}, fmt.Errorf("resolution must be between %dx%d and %dx%d", minVideoSide, minVideoSide, maxVideoWidth, maxVideoHeight)
+	}
+	if width%2 != 0 || height%2 != 0 {
+		return StreamQuality{}, fmt.Errorf("width and height must be even numbers")
+	}
+	if fps < 1 || fps > maxVideoFps {
+		return StreamQuality{}, fmt.Errorf("fps must be between 1 and %d", maxVideoFps)
+	}
+	if bitrate != 0 && (bitrate < minBitrate || bitrate > maxBitrate) {
+		return StreamQuality{}, fmt.Errorf("bitrate must be between %d and %d kbps", minBitrate, maxBitrate)
+	}
+
+	base = base.withDefaults()
+	return StreamQuality{
+		Preset:     QualityCustom,
+		SampleRate: base.SampleRate,
+		Channels:   base.Channels,
+		Width:      width,
+		Height:     height,
+		Fps:        fps,
+		Bitrate:    bitrate,
+	}, nil
+}
+
+// withDefaults fills the fields a stored profile lacks from the default profile
+func (q StreamQuality) withDefaults() StreamQuality {
+	def := DefaultQuality()
+	if q.Preset == "" {
+		q.Preset = def.Preset
+	}
+	if q.SampleRate <= 0 {
+		q.SampleRate = def.SampleRate
+	}
+	if q.Channels <= 0 {
+		q.Channels = def.Channels
+	}
+	if q.Width <= 0 || q.Height <= 0 {
+		q.Width, q.Height = def.Width, def.Height
+	}
+	if q.Fps <= 0 {
+		q.Fps = def.Fps
+	}
+	return q
+}
+
+// String describes the profile, e.g. "48 kHz stereo, 1280x720 @ 24 fps"
+func (q StreamQuality) String() string {
+	channels := "stereo"
+	if q.Channels == 1 {
+		channels = "mono"
+	}
+	text := fmt.Sprintf("%d kHz %s, %dx%d @ %d fps", q.SampleRate/1000, channels, q.Width, q.Height, q.Fps)
+	if q.Bitrate > 0 {
+		text += fmt.Sprintf(", %d kbps", q.Bitrate)
+	}
+	return text
+}
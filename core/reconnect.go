@@ -0,0 +1,219 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	ntg "shizumusic/ntgcalls"
+)
+
+// Reconnection backs off from reconnectDelay, doubling up to reconnectMaxDelay
+const (
+	reconnectAttempts = 5
+	reconnectDelay    = 2 * time.Second
+	reconnectMaxDelay = time.Minute
+)
+
+// connectionHistorySize is how many connection events are kept per chat
+const connectionHistorySize = 20
+
+// ConnectionEvent is a change of the connection of a chat, kept for debugging
+type ConnectionEvent struct {
+	Time         time.Time
+	State        string
+	Presentation bool   // the presentation connection changed, not the main one
+	Note         string // what the supervisor did about it
+}
+
+// ReconnectFailedHandler is called when a chat could not be reconnected and was left
+type ReconnectFailedHandler func(chatID int64, err error)
+
+// OnReconnectFailed registers a handler for chats the supervisor gave up on
+func (c *Calls) OnReconnectFailed(handler ReconnectFailedHandler) {
+	c.connectionsMu.Lock()
+	defer c.connectionsMu.Unlock()
+	c.reconnectFailedHandlers = append(c.reconnectFailedHandlers, handler)
+}
+
+// ConnectionHistory returns the latest connection events of a chat, the oldest first
+func (c *Calls) ConnectionHistory(chatID int64) []ConnectionEvent {
+	c.connectionsMu.Lock()
+	defer c.connectionsMu.Unlock()
+	return append([]ConnectionEvent{}, c.connectionHistory[chatID]...)
+}
+
+// IsReconnecting reports whether the supervisor is rejoining the call of a chat
+func (c *Calls) IsReconnecting(chatID int64) bool {
+	c.connectionsMu.Lock()
+	defer c.connectionsMu.Unlock()
+	return c.reconnecting[chatID]
+}
+
+func (c *Calls) recordConnection(chatID int64, event ConnectionEvent) {
+	event.Time = time.Now()
+
+	c.connectionsMu.Lock()
+	defer c.connectionsMu.Unlock()
+	history := append(c.connectionHistory[chatID], event)
+	if len(history) > connectionHistorySize {
+		history = history[len(history)-connectionHistorySize:]
+	}
+	c.connectionHistory[chatID] = history
+}
+
+// handleConnectionChange supervises the connection of every call
+// A dead main connection of a joined chat is rejoined in the background
+func (c *Calls) handleConnectionChange(chatID int64, info ntg.NetworkInfo) {
+	state := connectionStateName(info.State)
+	presentation := info.Kind == ntg.PresentationConnection
+	log.Printf(">> Connection changed for chat %d: %s (presentation: %v)", chatID, state, presentation)
+
+	dead := info.State == ntg.Failed || info.State == ntg.Timeout || info.State == ntg.Closed
+	event := ConnectionEvent{State: state, Presentation: presentation}
+
+	switch {
+	case !dead:
+	case c.IsPrivateCall(chatID):
+		event.Note = "private call hung up"
+		go c.HangUp(chatID)
+	case !c.IsActive(chatID):
+		// Left on purpose
+	case presentation:
+		event.Note = "presentation stopped"
+		go c.StopPresentation(chatID)
+	case c.startReconnecting(chatID):
+		event.Note = "reconnecting"
+		go c.reconnect(chatID)
+	}
+	c.recordConnection(chatID, event)
+}
+
+// startReconnecting claims the reconnection of a chat
+// It is false while the supervisor is already replacing the connection
+func (c *Calls) startReconnecting(chatID int64) bool {
+	c.connectionsMu.Lock()
+	defer c.connectionsMu.Unlock()
+	if c.reconnecting[chatID] {
+		return false
+	}
+	c.reconnecting[chatID] = true
+	return true
+}
+
+// reconnect rejoins the call of a chat with exponential backoff
+// The stream continues from its last known position, after reconnectAttempts the chat is left
+func (c *Calls) reconnect(chatID int64) {
+	defer func() {
+		c.connectionsMu.Lock()
+		delete(c.reconnecting, chatID)
+		c.connectionsMu.Unlock()
+	}()
+
+	c.activeSessionsMu.RLock()
+	s, ok := c.activeSessions[chatID]
+	var prev VCSession
+	if ok {
+		prev = *s
+	}
+	c.activeSessionsMu.RUnlock()
+	if !ok {
+		return
+	}
+	position := c.Position(chatID)
+
+	var err error
+	delay := reconnectDelay
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		time.Sleep(delay)
+		delay = min(delay*2, reconnectMaxDelay)
+
+		if !c.IsActive(chatID) {
+			return
+		}
+		log.Printf(">> Reconnecting chat %d, attempt %d/%d", chatID, attempt, reconnectAttempts)
+
+		if err = c.rejoin(chatID, &prev, position); err == nil {
+			c.recordConnection(chatID, ConnectionEvent{
+				State: "rejoined",
+				Note:  fmt.Sprintf("attempt %d/%d, resumed at %ds", attempt, reconnectAttempts, position),
+			})
+			log.Printf(">> Chat %d reconnected at %ds", chatID, position)
+			return
+		}
+		log.Printf(">> Reconnecting chat %d failed: %v", chatID, err)
+		c.recordConnection(chatID, ConnectionEvent{
+			State: "rejoin failed",
+			Note:  fmt.Sprintf("attempt %d/%d: %v", attempt, reconnectAttempts, err),
+		})
+	}
+
+	c.recordConnection(chatID, ConnectionEvent{State: "gave up", Note: "left the voice chat"})
+	c.LeaveVC(chatID)
+
+	c.connectionsMu.Lock()
+	handlers := append([]ReconnectFailedHandler{}, c.reconnectFailedHandlers...)
+	c.connectionsMu.Unlock()
+	for _, handler := range handlers {
+		handler(chatID, err)
+	}
+}
+
+// rejoin replaces the dead call of a chat and restores what the session was doing
+func (c *Calls) rejoin(chatID int64, prev *VCSession, position int) error {
+	a := c.assistant(chatID)
+	a.ntg.Stop(chatID)
+	if prev.groupCall != nil {
+		a.Client.PhoneLeaveGroupCall(tg.InputGroupCall(prev.groupCall), 0)
+	}
+	c.forgetBroadcast(chatID)
+	c.unassign(chatID)
+
+	// The player stays playing or paused while its call is replaced
+	if err := c.joinAny(chatID, prev.FilePath, prev.IsVideo, position); err != nil {
+		return err
+	}
+	c.updateSession(chatID, func(s *VCSession) { s.started = s.started || prev.started })
+
+	if prev.Presentation != "" {
+		err := c.StartPresentation(chatID, prev.Presentation)
+		if err == nil {
+			err = c.SeekStream(chatID, prev.FilePath, prev.IsVideo, position)
+		}
+		if err != nil {
+			log.Printf(">> Restoring presentation failed for chat %d: %v", chatID, err)
+		}
+	}
+	if c.Recording(chatID) != nil {
+		if err := c.capturePlayback(chatID); err != nil {
+			log.Printf(">> Restoring recording failed for chat %d: %v", chatID, err)
+		}
+	}
+	if prev.Muted {
+		if err := c.MuteVC(chatID); err != nil {
+			log.Printf(">> Restoring mute failed for chat %d: %v", chatID, err)
+		}
+	}
+	if c.holdPause(c.assistant(chatID), chatID) {
+		c.updateSession(chatID, func(s *VCSession) { s.pausedAt = time.Now() })
+	}
+	return nil
+}
+
+func connectionStateName(state ntg.ConnectionState) string {
+	switch state {
+	case ntg.Connecting:
+		return "connecting"
+	case ntg.Connected:
+		return "connected"
+	case ntg.Failed:
+		return "failed"
+	case ntg.Timeout:
+		return "timeout"
+	case ntg.Closed:
+		return "closed"
+	default:
+		return fmt.Sprintf("unknown (%d)", state)
+	}
+}
@@ -0,0 +1,303 @@
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	ntg "shizumusic/ntgcalls"
+)
+
+// Incoming audio arrives as 16-bit little-endian PCM in this format
+const (
+	recordSampleRate = 48000
+	recordChannels   = 2
+	recordBitrate    = "64k"
+)
+
+// recordBuffer is how many frame batches may wait for ffmpeg before new ones are dropped
+const recordBuffer = 1000
+
+// RecordLimits caps how large and how long a recording may grow
+type RecordLimits struct {
+	MaxSize     int64 // Bytes of encoded audio
+	MaxDuration time.Duration
+}
+
+// Recording is the incoming audio of a voice chat being encoded to an Opus file
+type Recording struct {
+	ChatID   int64
+	FilePath string
+	Started  time.Time
+	Ended    time.Time
+	Reason   string // Why the recording stopped, set once it ended
+
+	limits  RecordLimits
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	frames  chan []byte
+	written chan struct{}
+	stopped bool
+	mu      sync.Mutex
+}
+
+// RecordingHandler is called once a recording stopped and its file is complete
+type RecordingHandler func(rec *Recording)
+
+// Duration returns how long the recording ran
+func (r *Recording) Duration() time.Duration {
+	if r.Ended.IsZero() {
+		return time.Since(r.Started)
+	}
+	return r.Ended.Sub(r.Started)
+}
+
+// Size returns the bytes of encoded audio written so far
+func (r *Recording) Size() int64 {
+	info, err := os.Stat(r.FilePath)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// push queues a batch of frames, mixing every speaker into one PCM chunk
+func (r *Recording) push(frames []ntg.Frame) {
+	pcm := mixFrames(frames)
+	if len(pcm) == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	select {
+	case r.frames <- pcm:
+	default:
+		// ffmpeg fell behind, losing a few milliseconds beats blocking ntgcalls
+	}
+}
+
+// close stops taking frames and waits until ffmpeg received everything queued
+func (r *Recording) close() {
+	r.mu.Lock()
+	if !r.stopped {
+		r.stopped = true
+		close(r.frames)
+	}
+	r.mu.Unlock()
+	<-r.written
+}
+
+// write feeds queued audio to ffmpeg until the recording stops
+func (r *Recording) write() {
+	defer close(r.written)
+	for pcm := range r.frames {
+		if _, err := r.stdin.Write(pcm); err != nil {
+			log.Printf(">> Writing recording failed for chat %d: %v", r.ChatID, err)
+			break
+		}
+	}
+	// Drain whatever is left so a failed write never blocks push
+	for range r.frames {
+	}
+	r.stdin.Close()
+}
+
+// StartRecording captures the incoming audio of a joined call into filePath
+func (c *Calls) StartRecording(chatID int64, filePath string, limits RecordLimits) (*Recording, error) {
+	if !c.IsActive(chatID) {
+		return nil, fmt.Errorf("no active stream in chat %d", chatID)
+	}
+
+	cmd := exec.Command("ffmpeg", "-y", "-loglevel", "error",
+		"-f", "s16le", "-ar", fmt.Sprint(recordSampleRate), "-ac", fmt.Sprint(recordChannels), "-i", "pipe:0",
+		"-c:a", "libopus", "-b:a", recordBitrate, "-f", "ogg", filePath)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Recording{
+		ChatID:   chatID,
+		FilePath: filePath,
+		Started:  time.Now(),
+		limits:   limits,
+		cmd:      cmd,
+		stdin:    stdin,
+		frames:   make(chan []byte, recordBuffer),
+		written:  make(chan struct{}),
+	}
+
+	c.recordingsMu.Lock()
+	if _, ok := c.recordings[chatID]; ok {
+		c.recordingsMu.Unlock()
+		return nil, fmt.Errorf("chat %d is already being recorded", chatID)
+	}
+	c.recordings[chatID] = rec
+	c.recordingsMu.Unlock()
+
+	if err := cmd.Start(); err != nil {
+		c.dropRecording(rec)
+		return nil, fmt.Errorf("starting ffmpeg failed: %w", err)
+	}
+	go rec.write()
+
+	if err := c.capturePlayback(chatID); err != nil {
+		c.dropRecording(rec)
+		rec.close()
+		cmd.Wait()
+		os.Remove(filePath)
+		return nil, err
+	}
+	go c.watchRecording(rec)
+
+	log.Printf(">> Recording started for chat %d: %s", chatID, filePath)
+	return rec, nil
+}
+
+// capturePlayback hands out the remote audio of a call as raw frames instead of playing it
+func (c *Calls) capturePlayback(chatID int64) error {
+	err := c.assistant(chatID).ntg.SetStreamSources(chatID, ntg.PlaybackStream, ntg.MediaDescription{
+		Speaker: &ntg.AudioDescription{
+			MediaSource:  ntg.MediaSourceExternal,
+			SampleRate:   recordSampleRate,
+			ChannelCount: recordChannels,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("SetStreamSources failed: %w", err)
+	}
+	return nil
+}
+
+// StopRecording ends the recording of a chat, the recording handlers receive the file
+func (c *Calls) StopRecording(chatID int64) error {
+	if !c.finishRecording(chatID, "stopped") {
+		return fmt.Errorf("chat %d is not being recorded", chatID)
+	}
+	return nil
+}
+
+// Recording returns the running recording of a chat, nil when none
+func (c *Calls) Recording(chatID int64) *Recording {
+	c.recordingsMu.Lock()
+	defer c.recordingsMu.Unlock()
+	return c.recordings[chatID]
+}
+
+// OnRecordingEnd registers a handler for finished recordings
+func (c *Calls) OnRecordingEnd(handler RecordingHandler) {
+	c.recordingsMu.Lock()
+	defer c.recordingsMu.Unlock()
+	c.recordingHandlers = append(c.recordingHandlers, handler)
+}
+
+// dropRecording forgets a recording that failed to start
+func (c *Calls) dropRecording(rec *Recording) {
+	c.recordingsMu.Lock()
+	defer c.recordingsMu.Unlock()
+	if c.recordings[rec.ChatID] == rec {
+		delete(c.recordings, rec.ChatID)
+	}
+}
+
+// watchRecording stops a recording once it outgrows its limits
+func (c *Calls) watchRecording(rec *Recording) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if c.Recording(rec.ChatID) != rec {
+			return
+		}
+		switch {
+		case rec.limits.MaxDuration > 0 && rec.Duration() >= rec.limits.MaxDuration:
+			c.finishRecording(rec.ChatID, "duration limit reached")
+			return
+		case rec.limits.MaxSize > 0 && rec.Size() >= rec.limits.MaxSize:
+			c.finishRecording(rec.ChatID, "size limit reached")
+			return
+		}
+	}
+}
+
+// handleFrames routes incoming audio to the recording of its chat
+func (c *Calls) handleFrames(chatID int64, mode ntg.StreamMode, device ntg.StreamDevice, frames []ntg.Frame) {
+	if mode != ntg.PlaybackStream || device != ntg.SpeakerStream {
+		return
+	}
+	if rec := c.Recording(chatID); rec != nil {
+		rec.push(frames)
+	}
+}
+
+// finishRecording stops capturing, waits for ffmpeg to close the file and notifies the handlers
+func (c *Calls) finishRecording(chatID int64, reason string) bool {
+	c.recordingsMu.Lock()
+	rec, ok := c.recordings[chatID]
+	delete(c.recordings, chatID)
+	handlers := append([]RecordingHandler{}, c.recordingHandlers...)
+	c.recordingsMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	if c.IsActive(chatID) {
+		if err := c.assistant(chatID).ntg.SetStreamSources(chatID, ntg.PlaybackStream, ntg.MediaDescription{}); err != nil {
+			log.Printf(">> Releasing incoming audio failed for chat %d: %v", chatID, err)
+		}
+	}
+
+	rec.close()
+	if err := rec.cmd.Wait(); err != nil {
+		log.Printf(">> ffmpeg exited with error for recording of chat %d: %v", chatID, err)
+	}
+	rec.Ended = time.Now()
+	rec.Reason = reason
+
+	log.Printf(">> Recording ended for chat %d: %s", chatID, reason)
+	for _, handler := range handlers {
+		handler(rec)
+	}
+	return true
+}
+
+// mixFrames sums the PCM of every speaker in a batch, clipping at the 16-bit range
+func mixFrames(frames []ntg.Frame) []byte {
+	switch len(frames) {
+	case 0:
+		return nil
+	case 1:
+		return append([]byte(nil), frames[0].Data...)
+	}
+
+	size := 0
+	for _, frame := range frames {
+		if len(frame.Data) > size {
+			size = len(frame.Data)
+		}
+	}
+	size -= size % 2
+
+	mixed := make([]byte, size)
+	for i := 0; i < size; i += 2 {
+		sum := 0
+		for _, frame := range frames {
+			if i+1 < len(frame.Data) {
+				sum += int(int16(binary.LittleEndian.Uint16(frame.Data[i:])))
+			}
+		}
+		sum = max(math.MinInt16, min(math.MaxInt16, sum))
+		binary.LittleEndian.PutUint16(mixed[i:], uint16(int16(sum)))
+	}
+	return mixed
+}
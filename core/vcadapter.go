@@ -18,17 +18,22 @@ func (a *VCAdapter) JoinVC(ctx context.Context, chatID int64, file string, video
 	return a.calls.JoinVC(chatID, file, video)
 }
 
+func (a *VCAdapter) JoinVCAt(ctx context.Context, chatID int64, file string, video bool, offset int) error {
+	return a.calls.JoinVCAt(chatID, file, video, offset)
+}
+
 func (a *VCAdapter) LeaveVC(ctx context.Context, chatID int64, force bool) error {
 	return a.calls.LeaveVC(chatID)
 }
 
-func (a *VCAdapter) ChangeVC(ctx context.Context, chatID int64) error {
-	return a.calls.LeaveVC(chatID)
+func (a *VCAdapter) ChangeStream(ctx context.Context, chatID int64, file string, video bool) error {
+	return a.calls.ChangeStream(chatID, file, video)
 }
 
-func (a *VCAdapter) ReplayVC(ctx context.Context, chatID int64, file string, video bool) error {
-	if err := a.calls.LeaveVC(chatID); err != nil {
-		return err
-	}
-	return a.calls.JoinVC(chatID, file, video)
+func (a *VCAdapter) SeekStream(ctx context.Context, chatID int64, file string, video bool, offset int) error {
+	return a.calls.SeekStream(chatID, file, video, offset)
+}
+
+func (a *VCAdapter) Position(ctx context.Context, chatID int64) int {
+	return a.calls.Position(chatID)
 }
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("admin_commands", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		calls, player, queue := svc.Calls, svc.Player, svc.Queue
+
+		client.BotClient.AddMessageHandler("cmd:pause", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handlePause(calls))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:resume", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleResume(calls))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:mute", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleMute(calls))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:unmute", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleUnmute(calls))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:skip", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleSkip(player, queue))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:stop", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleStop(player))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:end", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleStop(player))(m)
+		})
+
+		RegisterCallback("ctrl|play", CallbackChatAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+			return handlePlayToggleCallback(cb, data, calls, db)
+		})
+
+		RegisterCallback("ctrl|mute", CallbackChatAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+			return handleMuteCallback(cb, data, calls, db, true)
+		})
+
+		RegisterCallback("ctrl|unmute", CallbackChatAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+			return handleMuteCallback(cb, data, calls, db, false)
+		})
+
+		RegisterCallback("ctrl|skip", CallbackChatAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+			return handleSkipCallback(cb, data, player, queue, db)
+		})
+
+		RegisterCallback("ctrl|replay", CallbackChatAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+			return handleReplayCallback(cb, data, player, db)
+		})
+
+		RegisterCallback("ctrl|end", CallbackChatAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+			return handleEndCallback(cb, data, player, db)
+		})
+	})
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                PAUSE / RESUME                              */
+/* -------------------------------------------------------------------------- */
+
+func handlePause(calls *core.Calls) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		if calls.IsPaused(m.ChatID()) {
+			_, _ = m.Respond("❌ The stream is already paused!")
+			return nil
+		}
+
+		if err := calls.PauseVC(m.ChatID()); err != nil {
+			log.Printf(">> Pause failed for chat %d: %v", m.ChatID(), err)
+			_, _ = m.Respond("❌ Failed to pause voice chat!")
+			return nil
+		}
+
+		_, _ = m.Respond(fmt.Sprintf("__VC Paused by:__ %s", senderMention(m)))
+		return nil
+	}
+}
+
+func handleResume(calls *core.Calls) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		if !calls.IsPaused(m.ChatID()) {
+			_, _ = m.Respond("❌ The stream is not paused!")
+			return nil
+		}
+
+		if err := calls.ResumeVC(m.ChatID()); err != nil {
+			log.Printf(">> Resume failed for chat %d: %v", m.ChatID(), err)
+			_, _ = m.Respond("❌ Failed to resume voice chat!")
+			return nil
+		}
+
+		_, _ = m.Respond(fmt.Sprintf("__VC Resumed by:__ %s", senderMention(m)))
+		return nil
+	}
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                MUTE / UNMUTE                               */
+/* -------------------------------------------------------------------------- */
+
+func handleMute(calls *core.Calls) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		if calls.IsMuted(m.ChatID()) {
+			_, _ = m.Respond("❌ The stream is already muted!")
+			return nil
+		}
+
+		if err := calls.MuteVC(m.ChatID()); err != nil {
+			log.Printf(">> Mute failed for chat %d: %v", m.ChatID(), err)
+			_, _ = m.Respond("❌ Failed to mute voice chat!")
+			return nil
+		}
+
+		_, _ = m.Respond(fmt.Sprintf("__VC Muted by:__ %s", senderMention(m)))
+		return nil
+	}
+}
+
+func handleUnmute(calls *core.Calls) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		if !calls.IsMuted(m.ChatID()) {
+			_, _ = m.Respond("❌ The stream is not muted!")
+			return nil
+		}
+
+		if err := calls.UnmuteVC(m.ChatID()); err != nil {
+			log.Printf(">> Unmute failed for chat %d: %v", m.ChatID(), err)
+			_, _ = m.Respond("❌ Failed to unmute voice chat!")
+			return nil
+		}
+
+		_, _ = m.Respond(fmt.Sprintf("__VC Unmuted by:__ %s", senderMention(m)))
+		return nil
+	}
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                 SKIP / STOP                                */
+/* -------------------------------------------------------------------------- */
+
+func handleSkip(player *utils.Player, queue *utils.QueueDB) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		if queue.GetQueueLength(m.ChatID()) <= 1 {
+			_, _ = m.Respond("❌ No more songs in queue to skip! Use /end or /stop to stop the VC.")
+			return nil
+		}
+
+		msg, err := m.Respond("Processing ...")
+		if err != nil {
+			return nil
+		}
+
+		if err := player.Skip(context.Background(), m.ChatID(), &tgMessage{msg: msg}); err != nil {
+			log.Printf(">> Skip failed for chat %d: %v", m.ChatID(), err)
+			_, _ = msg.Edit("❌ Failed to skip the current song!")
+		}
+		return nil
+	}
+}
+
+func handleStop(player *utils.Player) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		if err := player.Stop(context.Background(), m.ChatID()); err != nil {
+			log.Printf(">> Stop failed for chat %d: %v", m.ChatID(), err)
+		}
+
+		_, _ = m.Respond(fmt.Sprintf("__VC Stopped by:__ %s", senderMention(m)))
+		return nil
+	}
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                  CALLBACKS                                 */
+/* -------------------------------------------------------------------------- */
+
+// handlePlayToggleCallback pauses or resumes the stream
+// Data: ctrl|play|chat_id
+func handlePlayToggleCallback(cb *tg.CallbackQuery, data *CallbackData, calls *core.Calls, db *core.Database) error {
+	chatID, ok := ctrlActiveChat(cb, data, db)
+	if !ok {
+		return nil
+	}
+
+	action, toggle := "Paused", calls.PauseVC
+	if calls.IsPaused(chatID) {
+		action, toggle = "Resumed", calls.ResumeVC
+	}
+
+	if err := toggle(chatID); err != nil {
+		return err
+	}
+
+	_, _ = cb.Answer(action + "!")
+	_, _ = cb.Respond(fmt.Sprintf("__VC %s by:__ %s", action, callbackMention(cb)))
+	return nil
+}
+
+// handleMuteCallback mutes or unmutes the stream
+// Data: ctrl|mute|chat_id or ctrl|unmute|chat_id
+func handleMuteCallback(cb *tg.CallbackQuery, data *CallbackData, calls *core.Calls, db *core.Database, mute bool) error {
+	chatID, ok := ctrlActiveChat(cb, data, db)
+	if !ok {
+		return nil
+	}
+
+	if calls.IsMuted(chatID) == mute {
+		state := "unmuted"
+		if mute {
+			state = "muted"
+		}
+		_, _ = cb.Answer(fmt.Sprintf("The stream is already %s!", state), &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	action, toggle := "Unmuted", calls.UnmuteVC
+	if mute {
+		action, toggle = "Muted", calls.MuteVC
+	}
+
+	if err := toggle(chatID); err != nil {
+		return err
+	}
+
+	_, _ = cb.Answer(action + "!")
+	_, _ = cb.Respond(fmt.Sprintf("__VC %s by:__ %s", action, callbackMention(cb)))
+	return nil
+}
+
+// handleSkipCallback skips to the next track in queue
+// Data: ctrl|skip|chat_id
+func handleSkipCallback(cb *tg.CallbackQuery, data *CallbackData, player *utils.Player, queue *utils.QueueDB, db *core.Database) error {
+	chatID, ok := ctrlActiveChat(cb, data, db)
+	if !ok {
+		return nil
+	}
+
+	if queue.GetQueueLength(chatID) <= 1 {
+		_, _ = cb.Answer("No more songs in queue to skip!", &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	_, _ = cb.Answer("Skipped!")
+	msg, err := cb.Respond(fmt.Sprintf("__Skipped by:__ %s", callbackMention(cb)))
+	if err != nil {
+		return err
+	}
+	return player.Skip(context.Background(), chatID, &tgMessage{msg: msg})
+}
+
+// handleReplayCallback restarts the current track
+// Data: ctrl|replay|chat_id
+func handleReplayCallback(cb *tg.CallbackQuery, data *CallbackData, player *utils.Player, db *core.Database) error {
+	chatID, ok := ctrlActiveChat(cb, data, db)
+	if !ok {
+		return nil
+	}
+
+	_, _ = cb.Answer("Replaying!")
+	msg, err := cb.Respond(fmt.Sprintf("__Replaying by:__ %s", callbackMention(cb)))
+	if err != nil {
+		return err
+	}
+	return player.Replay(context.Background(), chatID, &tgMessage{msg: msg})
+}
+
+// handleEndCallback stops the stream and clears the queue
+// Data: ctrl|end|chat_id
+func handleEndCallback(cb *tg.CallbackQuery, data *CallbackData, player *utils.Player, db *core.Database) error {
+	chatID, ok := ctrlActiveChat(cb, data, db)
+	if !ok {
+		return nil
+	}
+
+	if err := player.Stop(context.Background(), chatID); err != nil {
+		log.Printf(">> Stop failed for chat %d: %v", chatID, err)
+	}
+
+	_, _ = cb.Answer("Left VC!")
+	_, _ = cb.Respond(fmt.Sprintf("__VC Stopped by:__ %s", callbackMention(cb)))
+	return nil
+}
+
+// senderMention returns a markdown mention of the message sender
+func senderMention(m *tg.NewMessage) string {
+	return fmt.Sprintf("[%s](tg://user?id=%d)", m.Sender.FirstName, m.Sender.ID)
+}
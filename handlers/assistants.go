@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/core"
+)
+
+func init() {
+	RegisterPlugin("assistant_commands", func(svc *Services) {
+
+		client := svc.Client
+		calls := svc.Calls
+
+		client.BotClient.AddMessageHandler("cmd:assistants", func(m *tg.NewMessage) error {
+			return core.SudoOnly(handleAssistants(calls))(m)
+		})
+	})
+}
+
+func handleAssistants(calls *core.Calls) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		statuses := calls.Assistants()
+
+		var text strings.Builder
+		text.WriteString(fmt.Sprintf("**🤖 Assistants:** `%d`\n\n", len(statuses)))
+		for _, s := range statuses {
+			name := s.Name
+			if s.Username != "" {
+				name = "@" + s.Username
+			}
+
+			state := "🟢 Online"
+			switch {
+			case !s.Connected:
+				state = "🔴 Disconnected"
+			case s.Failure != "":
+				state = "🟠 " + s.Failure
+			}
+
+			text.WriteString(fmt.Sprintf(
+				"**%d.** %s (`%d`)\n"+
+					"    **Status:** __%s__\n"+
+					"    **Voice Chats:** `%d`\n\n",
+				s.Index, name, s.ID, state, s.Chats,
+			))
+		}
+
+		_, _ = m.Reply(text.String())
+		return nil
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/core"
+)
+
+func init() {
+	RegisterPlugin("autoend_commands", func(svc *Services) {
+		client, db := svc.Client, svc.DB
+
+		client.BotClient.AddMessageHandler("cmd:autoend", func(m *tg.NewMessage) error {
+			return core.SudoOnly(handleAutoend(db))(m)
+		})
+	})
+}
+
+// defaultAutoendTimeout is how long a voice chat may idle when AUTOEND_TIMEOUT is unset
+const defaultAutoendTimeout = 5 * time.Minute
+
+// autoendTimeout returns how long a voice chat may stay idle before it is ended
+// Set AUTOEND_TIMEOUT in the environment, in minutes, to change it
+func autoendTimeout() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("AUTOEND_TIMEOUT")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultAutoendTimeout
+}
+
+func handleAutoend(db *core.Database) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		usage := "**Usage:**\n\n" +
+			"__To turn off autoend:__ `/autoend off`\n" +
+			"__To turn on autoend:__ `/autoend on`"
+
+		args := strings.Fields(m.Args())
+		if len(args) != 1 {
+			_, _ = m.Reply(usage)
+			return nil
+		}
+
+		autoend, _ := db.GetAutoend()
+		switch strings.ToLower(args[0]) {
+		case "on":
+			if autoend {
+				_, _ = m.Reply("AutoEnd is already enabled.")
+				return nil
+			}
+			if err := db.SetAutoend(true); err != nil {
+				_, _ = m.Reply(fmt.Sprintf("❌ Failed to enable AutoEnd: %v", err))
+				return nil
+			}
+			_, _ = m.Reply(fmt.Sprintf(
+				"AutoEnd Enabled! Now I will automatically end the stream after `%d` minutes "+
+					"when the VC is paused, empty or idle.",
+				int(autoendTimeout().Minutes()),
+			))
+
+		case "off":
+			if !autoend {
+				_, _ = m.Reply("AutoEnd is already disabled.")
+				return nil
+			}
+			if err := db.SetAutoend(false); err != nil {
+				_, _ = m.Reply(fmt.Sprintf("❌ Failed to disable AutoEnd: %v", err))
+				return nil
+			}
+			_, _ = m.Reply("AutoEnd Disabled!")
+
+		default:
+			_, _ = m.Reply(usage)
+		}
+		return nil
+	}
+}
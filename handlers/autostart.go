@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+)
+
+func init() {
+	RegisterPlugin("autostart_commands", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		calls := svc.Calls
+
+		client.BotClient.AddMessageHandler("cmd:autostart", func(m *tg.NewMessage) error {
+			return core.AdminOnly(handleAutoStart(calls, db))(m)
+		})
+	})
+}
+
+// maxCallTitle is the longest group call title Telegram accepts
+const maxCallTitle = 64
+
+func handleAutoStart(calls *core.Calls, db *core.Database) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		chatID := m.ChatID()
+		settings := db.GetVCSettings(chatID)
+
+		args := strings.Fields(m.Args())
+		if len(args) == 0 {
+			_, _ = m.Respond(autoStartUsage(settings))
+			return nil
+		}
+
+		switch option := strings.ToLower(args[0]); option {
+		case "on", "off":
+			settings.AutoStart = option == "on"
+
+		case "title":
+			title := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(m.Args()), args[0]))
+			if len([]rune(title)) > maxCallTitle {
+				_, _ = m.Respond(fmt.Sprintf("❌ The title can be at most `%d` characters!", maxCallTitle))
+				return nil
+			}
+			settings.Title = title
+
+		case "joinas":
+			if len(args) != 2 {
+				_, _ = m.Respond(autoStartUsage(settings))
+				return nil
+			}
+			joinAs, err := parseJoinAs(calls, chatID, m.SenderID(), args[1])
+			if err != nil {
+				_, _ = m.Respond(fmt.Sprintf("❌ %v", err))
+				return nil
+			}
+			settings.JoinAs = joinAs
+
+		case "endcall":
+			value := ""
+			if len(args) == 2 {
+				value = strings.ToLower(args[1])
+			}
+			if value != "on" && value != "off" {
+				_, _ = m.Respond(autoStartUsage(settings))
+				return nil
+			}
+			settings.EndCall = value == "on"
+
+		default:
+			_, _ = m.Respond(autoStartUsage(settings))
+			return nil
+		}
+
+		if err := db.SetVCSettings(chatID, settings); err != nil {
+			log.Printf(">> Saving voice chat settings failed for chat %d: %v", chatID, err)
+			_, _ = m.Respond("❌ Failed to save the voice chat settings!")
+			return nil
+		}
+
+		_, _ = m.Respond(fmt.Sprintf("%s\n\n__Changed by:__ %s", autoStartSettings(settings), senderMention(m)))
+		return nil
+	}
+}
+
+// parseJoinAs checks that the sender owns the given channel and that
+// the assistant is offered to join the calls of the chat as it
+// "me" joins as the assistant itself
+func parseJoinAs(calls *core.Calls, chatID, senderID int64, arg string) (string, error) {
+	if strings.EqualFold(arg, "me") {
+		return "", nil
+	}
+
+	assistant := calls.AssistantClient(chatID)
+	peer, err := assistant.ResolvePeer(strings.TrimPrefix(arg, "@"))
+	if err != nil {
+		return "", fmt.Errorf("The assistant can't find `%s`, use the @username of a channel it is an admin of.", arg)
+	}
+	channel, ok := peer.(*tg.InputPeerChannel)
+	if !ok {
+		return "", fmt.Errorf("`%s` is not a channel!", arg)
+	}
+
+	member, err := assistant.GetChatMember(channel, senderID)
+	if err != nil || (member.Status != tg.Creator && member.Status != tg.Admin) {
+		return "", fmt.Errorf("Only admins of `%s` can make the assistant join as it!", arg)
+	}
+
+	chatPeer, err := assistant.ResolvePeer(chatID)
+	if err != nil {
+		return "", errors.New("The assistant can't see this chat, add it first.")
+	}
+	offered, err := assistant.PhoneGetGroupCallJoinAs(chatPeer)
+	if err != nil {
+		log.Printf(">> Getting the join as peers failed for chat %d: %v", chatID, err)
+		return "", errors.New("Failed to check who the assistant can join as!")
+	}
+	for _, p := range offered.Peers {
+		if p, ok := p.(*tg.PeerChannel); ok && p.ChannelID == channel.ChannelID {
+			return "@" + strings.TrimPrefix(arg, "@"), nil
+		}
+	}
+	return "", fmt.Errorf("The assistant can't join voice chats here as `%s`, make it an admin of the channel first.", arg)
+}
+
+func autoStartSettings(settings core.VCSettings) string {
+	onOff := func(on bool) string {
+		if on {
+			return "on"
+		}
+		return "off"
+	}
+	title, joinAs := settings.Title, settings.JoinAs
+	if title == "" {
+		title = "none"
+	}
+	if joinAs == "" {
+		joinAs = "assistant"
+	}
+
+	return fmt.Sprintf(
+		"**🎙️ Voice Chat Settings**\n\n"+
+			"**Auto start:** `%s`\n"+
+			"**Title:** `%s`\n"+
+			"**Join as:** `%s`\n"+
+			"**End call when done:** `%s`",
+		onOff(settings.AutoStart), title, joinAs, onOff(settings.EndCall),
+	)
+}
+
+func autoStartUsage(settings core.VCSettings) string {
+	return autoStartSettings(settings) + "\n\n" +
+		"**Usage:**\n\n" +
+		"__Start the voice chat when none is active:__ `/autostart on|off`\n" +
+		"__Title of started voice chats:__ `/autostart title <text>`\n" +
+		"__Join as a channel:__ `/autostart joinas <@channel|me>`\n" +
+		"__End a started voice chat once the queue is over:__ `/autostart endcall on|off`\n\n" +
+		"__The assistant needs the \"Manage Video Chats\" right to start voice chats.__"
+}
@@ -12,7 +12,8 @@ import (
 
 // init registers the basic bot handlers plugin
 func init() {
-	RegisterPlugin("basic_commands", func(client *core.Client, db *core.Database) {
+	RegisterPlugin("basic_commands", func(svc *Services) {
+		client, db := svc.Client, svc.DB
 		client.BotClient.AddMessageHandler("/start", func(m *tg.NewMessage) error {
 			return handleStart(m, client, db)
 		})
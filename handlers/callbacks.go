@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/helpers"
+)
+
+func init() {
+	RegisterPlugin("callback_router", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		calls, player, queue := svc.Calls, svc.Player, svc.Queue
+		pages := newPages(client, db)
+
+		registerMenuCallbacks(client)
+		registerPageCallbacks(client, db, calls, queue, pages)
+		registerFavoriteCallbacks(db, player, queue, pages)
+
+		client.BotClient.AddCallbackHandler(string(tg.OnCallbackQuery), func(cb *tg.CallbackQuery) error {
+			return dispatchCallback(cb, client, db)
+		})
+	})
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                   ROUTER                                   */
+/* -------------------------------------------------------------------------- */
+
+// CallbackData is a parsed pipe separated callback payload
+// "ctrl|skip|-100123" becomes Route "ctrl" with Args ["skip", "-100123"]
+type CallbackData struct {
+	Route string
+	Args  []string
+}
+
+// Arg returns the argument at index i or an empty string
+func (d *CallbackData) Arg(i int) string {
+	if i < 0 || i >= len(d.Args) {
+		return ""
+	}
+	return d.Args[i]
+}
+
+// Int parses the argument at index i as int
+func (d *CallbackData) Int(i int) (int, error) {
+	return strconv.Atoi(d.Arg(i))
+}
+
+// Int64 parses the argument at index i as int64
+func (d *CallbackData) Int64(i int) (int64, error) {
+	return strconv.ParseInt(d.Arg(i), 10, 64)
+}
+
+// parseCallbackData splits a raw callback payload into route and arguments
+func parseCallbackData(raw string) *CallbackData {
+	parts := strings.Split(raw, "|")
+	return &CallbackData{Route: parts[0], Args: parts[1:]}
+}
+
+// CallbackHandler handles a routed callback query
+type CallbackHandler func(cb *tg.CallbackQuery, data *CallbackData) error
+
+// CallbackPermission decides who may press a button
+type CallbackPermission int
+
+const (
+	// CallbackAnyone lets every user press the button
+	CallbackAnyone CallbackPermission = iota
+	// CallbackAuth requires playback rights in the chat, see core.IsAuthorized
+	CallbackAuth
+	// CallbackChatAuth is CallbackAuth for buttons naming their chat in the second argument,
+	// that chat must be the one the button was pressed in
+	CallbackChatAuth
+	// CallbackSudo requires a sudo user
+	CallbackSudo
+	// CallbackOwner requires the presser to be the user ID in the second argument
+	CallbackOwner
+)
+
+type callbackRoute struct {
+	permission CallbackPermission
+	handler    CallbackHandler
+}
+
+var (
+	callbackRoutes   = make(map[string]callbackRoute)
+	callbackRoutesMu sync.RWMutex
+)
+
+// RegisterCallback routes payloads starting with key to handler
+// Key is either a route ("queue") or a route with its action ("ctrl|skip"),
+// the more specific key wins
+func RegisterCallback(key string, permission CallbackPermission, handler CallbackHandler) {
+	callbackRoutesMu.Lock()
+	defer callbackRoutesMu.Unlock()
+	callbackRoutes[key] = callbackRoute{permission: permission, handler: handler}
+}
+
+func lookupCallback(data *CallbackData) (callbackRoute, bool) {
+	callbackRoutesMu.RLock()
+	defer callbackRoutesMu.RUnlock()
+
+	if len(data.Args) > 0 {
+		if route, ok := callbackRoutes[data.Route+"|"+data.Args[0]]; ok {
+			return route, true
+		}
+	}
+	route, ok := callbackRoutes[data.Route]
+	return route, ok
+}
+
+// dispatchCallback routes a callback query after checking its permission
+func dispatchCallback(cb *tg.CallbackQuery, client *core.Client, db *core.Database) error {
+	userID := cb.GetSenderID()
+	if config.Cfg.IsBanned(userID) {
+		return nil
+	}
+
+	data := parseCallbackData(cb.DataString())
+	route, ok := lookupCallback(data)
+	if !ok {
+		_, _ = cb.Answer("Unknown action!", &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	switch route.permission {
+	case CallbackChatAuth:
+		// Payloads can be forged, rights in one chat must not reach another
+		if chatID, err := data.Int64(1); err != nil || chatID != cb.GetChatID() {
+			_, _ = cb.Answer("This button doesn't belong to this chat!", &tg.CallbackOptions{Alert: true})
+			return nil
+		}
+		fallthrough
+	case CallbackAuth:
+		if !core.IsAuthorized(client.BotClient, db, cb.GetChatID(), userID) {
+			_, _ = cb.Answer("You need to be an admin or an authorized user to do this!", &tg.CallbackOptions{Alert: true})
+			return nil
+		}
+	case CallbackSudo:
+		if !config.Cfg.IsSudo(userID) {
+			_, _ = cb.Answer("This button is only for sudo users!", &tg.CallbackOptions{Alert: true})
+			return nil
+		}
+	case CallbackOwner:
+		if ownerID, err := data.Int64(1); err != nil || ownerID != userID {
+			_, _ = cb.Answer("This button is not for you!", &tg.CallbackOptions{Alert: true})
+			return nil
+		}
+	}
+
+	if err := route.handler(cb, data); err != nil {
+		log.Printf(">> Callback %q failed: %v", cb.DataString(), err)
+		_, _ = cb.Answer("Something went wrong!", &tg.CallbackOptions{Alert: true})
+	}
+	return nil
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                 MENU ROUTES                                */
+/* -------------------------------------------------------------------------- */
+
+// registerMenuCallbacks routes the close, player and help menu buttons
+func registerMenuCallbacks(client *core.Client) {
+
+	// Data: close
+	RegisterCallback("close", CallbackAnyone, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		_, _ = cb.Answer("Closed!")
+		_, err := cb.Delete()
+		return err
+	})
+
+	// Data: controls|video_id|chat_id
+	RegisterCallback("controls", CallbackAnyone, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		chatID, err := data.Int64(1)
+		if err != nil {
+			return err
+		}
+		_, _ = cb.Answer("")
+		return editCallbackMarkup(cb, helpers.Buttons.ControlsMarkup(data.Arg(0), chatID))
+	})
+
+	// Data: player|video_id|chat_id
+	RegisterCallback("player", CallbackAnyone, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		chatID, err := data.Int64(1)
+		if err != nil {
+			return err
+		}
+		me, err := client.BotClient.GetMe()
+		if err != nil {
+			return err
+		}
+		_, _ = cb.Answer("")
+		return editCallbackMarkup(cb, helpers.Buttons.PlayerMarkup(chatID, data.Arg(0), me.Username))
+	})
+
+	// Data: help|category
+	RegisterCallback("help", CallbackAnyone, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		return handleHelpCallback(cb, data, client)
+	})
+
+	// Data: source
+	RegisterCallback("source", CallbackAnyone, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		_, _ = cb.Answer("")
+		_, err := cb.Edit(helpers.TextTemplates.Source(), &tg.SendOptions{ReplyMarkup: helpers.Buttons.SourceMarkup()})
+		return err
+	})
+}
+
+func handleHelpCallback(cb *tg.CallbackQuery, data *CallbackData, client *core.Client) error {
+	me, err := client.BotClient.GetMe()
+	if err != nil {
+		return err
+	}
+
+	var (
+		text   string
+		markup tg.ReplyMarkup = helpers.Buttons.HelpBack()
+	)
+
+	switch data.Arg(0) {
+	case "admin":
+		text = helpers.TextTemplates.HelpAdmin()
+	case "user":
+		text = helpers.TextTemplates.HelpUser()
+	case "sudo":
+		text = helpers.TextTemplates.HelpSudo()
+	case "others":
+		text = helpers.TextTemplates.HelpOthers()
+	case "owner":
+		text = helpers.TextTemplates.HelpOwners()
+	case "back":
+		text = fmt.Sprintf(helpers.TextTemplates.HelpPM(), "@"+me.Username)
+		markup = helpers.Buttons.HelpPMMarkup()
+	case "start":
+		name := "there"
+		if cb.Sender != nil {
+			name = cb.Sender.FirstName
+		}
+		text = fmt.Sprintf(helpers.TextTemplates.StartPM(), name, me.FirstName, me.Username)
+		markup = helpers.Buttons.StartPMMarkup(me.Username)
+	default:
+		_, _ = cb.Answer("Unknown category!", &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	_, _ = cb.Answer("")
+	_, err = cb.Edit(text, &tg.SendOptions{ReplyMarkup: markup})
+	return err
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                   HELPERS                                  */
+/* -------------------------------------------------------------------------- */
+
+// callbackMention returns a markdown mention of the user who pressed a button
+func callbackMention(cb *tg.CallbackQuery) string {
+	name := "User"
+	if cb.Sender != nil {
+		name = cb.Sender.FirstName
+	}
+	return fmt.Sprintf("[%s](tg://user?id=%d)", name, cb.GetSenderID())
+}
+
+// editCallbackMarkup swaps the buttons of a callback message, keeping its text
+func editCallbackMarkup(cb *tg.CallbackQuery, markup tg.ReplyMarkup) error {
+	msg, err := cb.GetMessage()
+	if err != nil {
+		return err
+	}
+
+	opts := &tg.SendOptions{ReplyMarkup: markup}
+	if msg.Message != nil {
+		opts.Entities = msg.Message.Entities
+	}
+	_, err = cb.Edit(msg.Text(), opts)
+	return err
+}
+
+// pageIndex moves page by step and wraps around total pages
+func pageIndex(page, step, total int) int {
+	if total <= 0 {
+		return 0
+	}
+	page = (page + step) % total
+	if page < 0 {
+		page += total
+	}
+	return page
+}
+
+// pageStep turns a navigation action into a page step
+func pageStep(action string) int {
+	switch action {
+	case "next":
+		return 1
+	case "prev":
+		return -1
+	}
+	return 0
+}
+
+// pageCount returns how many pages items need at size per page
+func pageCount(items, size int) int {
+	return (items + size - 1) / size
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("p2p_commands", func(svc *Services) {
+
+		client := svc.Client
+		calls, player := svc.Calls, svc.Player
+
+		requests := &callRequests{requests: make(map[int64]callRequest)}
+		calls.EnablePrivateCalls(func(userID int64) {
+			answerPrivateCall(calls, requests, userID)
+		})
+
+		// A track is done with once it was heard or nobody called back in time
+		calls.OnPrivateCallEnd(func(userID int64) {
+			if file := requests.take(userID); file != "" {
+				player.CleanupTracks([]utils.QueueItem{{File: file}})
+			}
+		})
+		go every(svc.Done(), time.Minute, func() {
+			for _, file := range requests.expire(calls.IsPrivateCall) {
+				player.CleanupTracks([]utils.QueueItem{{File: file}})
+			}
+		})
+
+		client.BotClient.AddMessageHandler("cmd:callme", func(m *tg.NewMessage) error {
+			return handleCallMe(m, client, calls, player, requests)
+		})
+	})
+}
+
+// callRequestTTL is how long a missed /callme track can be heard by calling the assistant back
+const callRequestTTL = 10 * time.Minute
+
+// callRequest is the track a user asked to hear in a private call
+type callRequest struct {
+	File    string
+	Title   string
+	Expires time.Time
+}
+
+// callRequests keeps the last /callme track of each user
+type callRequests struct {
+	requests map[int64]callRequest
+	mu       sync.Mutex
+}
+
+// set stores the track of a user and returns the file it replaced, "" when none
+func (r *callRequests) set(userID int64, request callRequest) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous := r.requests[userID].File
+	r.requests[userID] = request
+	if previous == request.File {
+		return ""
+	}
+	return previous
+}
+
+// get returns the pending track of a user unless it expired
+func (r *callRequests) get(userID int64) (callRequest, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	request, ok := r.requests[userID]
+	if !ok || time.Now().After(request.Expires) {
+		return callRequest{}, false
+	}
+	return request, true
+}
+
+// take drops the track of a user and returns its file, "" when none
+func (r *callRequests) take(userID int64) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	file := r.requests[userID].File
+	delete(r.requests, userID)
+	return file
+}
+
+// expire drops the tracks nobody called back for in time and returns their files
+// Tracks of users still in a call are kept until the call ends
+func (r *callRequests) expire(inCall func(userID int64) bool) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var files []string
+	now := time.Now()
+	for userID, request := range r.requests {
+		if now.After(request.Expires) && !inCall(userID) {
+			files = append(files, request.File)
+			delete(r.requests, userID)
+		}
+	}
+	return files
+}
+
+// handleCallMe calls the sender from the assistant and plays them a song
+// Works in the bot's PM only, the track stays available for a call back when missed
+func handleCallMe(
+	m *tg.NewMessage,
+	client *core.Client,
+	calls *core.Calls,
+	player *utils.Player,
+	requests *callRequests,
+) error {
+	if !m.IsPrivate() || m.Sender == nil || config.Cfg.IsBanned(m.SenderID()) {
+		return nil
+	}
+
+	userID := m.Sender.ID
+	query := strings.TrimSpace(m.Args())
+	if query == "" {
+		_, _ = m.Reply("**Usage:** `/callme <song name or YouTube URL>`\n\n" +
+			"The assistant calls you and plays the song in a private call.")
+		return nil
+	}
+	if calls.IsPrivateCall(userID) {
+		_, _ = m.Reply("❌ You are already in a call with the assistant, hang up first!")
+		return nil
+	}
+
+	status, err := m.Reply("🔍 Searching ...")
+	if err != nil {
+		return nil
+	}
+	msgWrapper := &tgMessage{msg: status}
+	ctx := context.Background()
+
+	link, title := query, query
+	if strings.Contains(query, "youtube.com") || strings.Contains(query, "youtu.be") {
+		if info, err := utils.YTube.GetVideoInfo(ctx, utils.ExtractVideoIDFromLink(query)); err == nil && info != nil {
+			title = info.Title
+		}
+	} else {
+		results, err := utils.YTube.GetData(ctx, query, true, 1)
+		if err != nil || len(results) == 0 {
+			_ = msgWrapper.Edit(ctx, "❌ No results found. Try a different query.")
+			return nil
+		}
+		link, title = results[0].Link, results[0].Title
+	}
+
+	_ = msgWrapper.Edit(ctx, fmt.Sprintf("⬇️ Downloading `%s` ...", title))
+	filePath, err := utils.YTube.Download(ctx, link, false, false)
+	if err != nil {
+		_ = msgWrapper.Edit(ctx, fmt.Sprintf("❌ Download failed: %v", err))
+		return nil
+	}
+
+	previous := requests.set(userID, callRequest{
+		File:    filePath,
+		Title:   title,
+		Expires: time.Now().Add(callRequestTTL),
+	})
+	if previous != "" {
+		player.CleanupTracks([]utils.QueueItem{{File: previous}})
+	}
+
+	// The assistant can only call users it can resolve
+	if m.Sender.Username != "" {
+		client.UserClient.ResolveUsername(m.Sender.Username)
+	}
+
+	_ = msgWrapper.Edit(ctx, fmt.Sprintf("📞 Calling you to play `%s` ...", title))
+	if err := calls.CallUser(userID, filePath, false); err != nil {
+		log.Printf(">> Private call to user %d failed: %v", userID, err)
+		_ = msgWrapper.Edit(ctx, fmt.Sprintf(
+			"❌ Couldn't reach you: %v\n\n"+
+				"Call the assistant yourself within `%d` minutes to hear `%s`.",
+			err, int(callRequestTTL.Minutes()), title,
+		))
+		return nil
+	}
+
+	_ = msgWrapper.Edit(ctx, fmt.Sprintf("📞 Playing `%s` in your private call!", title))
+	return nil
+}
+
+// answerPrivateCall picks up a call to the assistant when the caller has a track waiting
+func answerPrivateCall(calls *core.Calls, requests *callRequests, userID int64) {
+	request, ok := requests.get(userID)
+	if !ok || config.Cfg.IsBanned(userID) {
+		calls.DeclineCall(userID)
+		return
+	}
+
+	if err := calls.AnswerCall(userID, request.File, false); err != nil {
+		log.Printf(">> Answering private call of user %d failed: %v", userID, err)
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("control_commands", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		player, queue := svc.Player, svc.Queue
+
+		client.BotClient.AddMessageHandler("cmd:loop", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleLoop(db, queue))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:seek", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleSeek(player, false))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:seekback", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleSeek(player, true))(m)
+		})
+
+		RegisterCallback("ctrl|loop", CallbackChatAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+			return handleLoopCallback(cb, data, queue, db)
+		})
+
+		RegisterCallback("ctrl|fseek", CallbackChatAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+			return handleSeekCallback(cb, data, player, db)
+		})
+
+		RegisterCallback("ctrl|bseek", CallbackChatAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+			return handleSeekCallback(cb, data, player, db)
+		})
+	})
+}
+
+const (
+	// maxLoop is the highest loop count a chat can set
+	maxLoop = 10
+	// buttonSeek is how far the seek buttons move playback
+	buttonSeek = 10
+)
+
+/* -------------------------------------------------------------------------- */
+/*                                    LOOP                                    */
+/* -------------------------------------------------------------------------- */
+
+func handleLoop(db *core.Database, queue *utils.QueueDB) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		usage := fmt.Sprintf(
+			"**Usage:** `/loop <1-%d|off>`\n\n"+
+				"Maximum loop range is **%d**. Give **off** to disable loop.",
+			maxLoop, maxLoop,
+		)
+
+		args := strings.Fields(m.Args())
+		if len(args) == 0 {
+			_, _ = m.Respond(usage)
+			return nil
+		}
+
+		chatID := m.ChatID()
+		currentLoop, _ := db.GetLoop(chatID)
+		mention := senderMention(m)
+
+		arg := strings.ToLower(args[0])
+		if arg == "off" || arg == "0" {
+			if currentLoop == 0 {
+				_, _ = m.Respond("❌ There is no active loop in this chat!")
+				return nil
+			}
+			db.SetLoop(chatID, 0)
+			_, _ = m.Respond(fmt.Sprintf(
+				"__Loop disabled by:__ %s\n\nPrevious loop was: `%d`",
+				mention, currentLoop,
+			))
+			return nil
+		}
+
+		if que := queue.GetCurrent(chatID); que != nil && que.IsLive() {
+			_, _ = m.Respond("❌ Live streams can't be looped!")
+			return nil
+		}
+
+		count, err := strconv.Atoi(arg)
+		if err != nil || count < 1 || count > maxLoop {
+			_, _ = m.Respond(usage)
+			return nil
+		}
+
+		finalLoop := currentLoop + count
+		if finalLoop > maxLoop {
+			finalLoop = maxLoop
+		}
+		db.SetLoop(chatID, finalLoop)
+
+		_, _ = m.Respond(fmt.Sprintf(
+			"__Loop set to:__ `%d`\n__By:__ %s\n\nPrevious loop was: `%d`",
+			finalLoop, mention, currentLoop,
+		))
+		return nil
+	}
+}
+
+// handleLoopCallback toggles the loop between off and the maximum
+// Data: ctrl|loop|chat_id
+func handleLoopCallback(cb *tg.CallbackQuery, data *CallbackData, queue *utils.QueueDB, db *core.Database) error {
+	chatID, ok := ctrlActiveChat(cb, data, db)
+	if !ok {
+		return nil
+	}
+
+	currentLoop, _ := db.GetLoop(chatID)
+	if currentLoop != 0 {
+		db.SetLoop(chatID, 0)
+		_, _ = cb.Answer("Loop disabled!")
+		return nil
+	}
+
+	if que := queue.GetCurrent(chatID); que != nil && que.IsLive() {
+		_, _ = cb.Answer("Live streams can't be looped!", &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	db.SetLoop(chatID, maxLoop)
+	_, _ = cb.Answer(fmt.Sprintf("Loop set to %d!", maxLoop))
+	return nil
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                    SEEK                                    */
+/* -------------------------------------------------------------------------- */
+
+func handleSeek(player *utils.Player, backward bool) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		args := strings.Fields(m.Args())
+		seconds := 0
+		if len(args) > 0 {
+			seconds, _ = strconv.Atoi(args[0])
+		}
+		if seconds == 0 {
+			_, _ = m.Respond(
+				"**Usage:**\n" +
+					"__- Seek 10 secs forward >__ `/seek 10`\n" +
+					"__- Seek 10 secs backward >__ `/seekback 10`",
+			)
+			return nil
+		}
+		if backward && seconds > 0 {
+			seconds = -seconds
+		}
+
+		msg, err := m.Respond("⏩ Seeking ...")
+		if err != nil {
+			return nil
+		}
+
+		position, err := player.Seek(context.Background(), m.ChatID(), seconds)
+		if err != nil {
+			_, _ = msg.Edit(seekErrorText(err))
+			return nil
+		}
+
+		direction := "forward"
+		if seconds < 0 {
+			direction = "backward"
+			seconds = -seconds
+		}
+
+		mention := senderMention(m)
+		_, _ = msg.Edit(fmt.Sprintf(
+			"__Seeked `%s` %s!__\n\n__By:__ %s\n__Position:__ `%s`",
+			utils.SecsToMins(seconds), direction, mention,
+			utils.SecsToMins(position),
+		))
+		return nil
+	}
+}
+
+// handleSeekCallback seeks the current track by buttonSeek seconds
+// Data: ctrl|fseek|chat_id or ctrl|bseek|chat_id
+func handleSeekCallback(cb *tg.CallbackQuery, data *CallbackData, player *utils.Player, db *core.Database) error {
+	chatID, ok := ctrlActiveChat(cb, data, db)
+	if !ok {
+		return nil
+	}
+
+	seconds := buttonSeek
+	if data.Arg(0) == "bseek" {
+		seconds = -buttonSeek
+	}
+
+	position, err := player.Seek(context.Background(), chatID, seconds)
+	if err != nil {
+		_, _ = cb.Answer(seekErrorText(err), &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	_, _ = cb.Answer(fmt.Sprintf("Seeked to %s", utils.SecsToMins(position)))
+	return nil
+}
+
+// seekErrorText turns a seek error into a message for the user
+func seekErrorText(err error) string {
+	if utils.IsUserException(err) {
+		return "❌ " + err.Error()
+	}
+	return "❌ Something went wrong while seeking!"
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                   HELPERS                                  */
+/* -------------------------------------------------------------------------- */
+
+// ctrlActiveChat parses the chat of ctrl|action|chat_id callback data
+// Answers the callback itself when nothing is playing there
+func ctrlActiveChat(cb *tg.CallbackQuery, data *CallbackData, db *core.Database) (int64, bool) {
+	chatID, err := data.Int64(1)
+	if err != nil {
+		_, _ = cb.Answer("Invalid callback data!", &tg.CallbackOptions{Alert: true})
+		return 0, false
+	}
+
+	if active, _ := db.IsActiveVC(chatID); !active {
+		_, _ = cb.Answer("Nothing is playing right now!", &tg.CallbackOptions{Alert: true})
+		return 0, false
+	}
+	return chatID, true
+}
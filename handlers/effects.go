@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("effect_commands", func(svc *Services) {
+
+		client := svc.Client
+		calls, player, queue := svc.Calls, svc.Player, svc.Queue
+
+		client.BotClient.AddMessageHandler("cmd:bass", func(m *tg.NewMessage) error {
+			return core.AdminOnly(handleBass(calls, player))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:speed", func(m *tg.NewMessage) error {
+			return core.AdminOnly(handleSpeed(calls, player, queue))(m)
+		})
+	})
+}
+
+// Effect ranges accepted from users
+const (
+	maxBassBoost = 10
+	minSpeed     = 0.5
+	maxSpeed     = 2.0
+)
+
+func handleBass(calls *core.Calls, player *utils.Player) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		args := strings.Fields(m.Args())
+		level := -1
+		if len(args) > 0 {
+			if n, err := strconv.Atoi(args[0]); err == nil {
+				level = n
+			}
+		}
+		if level < 0 || level > maxBassBoost {
+			_, _ = m.Respond(fmt.Sprintf(
+				"**Usage:** `/bass <0-%d>`\n\nGive **0** to disable bass boost.",
+				maxBassBoost,
+			))
+			return nil
+		}
+
+		effects := calls.Effects(m.ChatID())
+		effects.BassBoost = level
+
+		if err := applyEffects(m, calls, player, effects); err != nil {
+			return nil
+		}
+
+		_, _ = m.Respond(fmt.Sprintf(
+			"__Bass boost set to:__ `%d`\n__By:__ %s",
+			level, senderMention(m),
+		))
+		return nil
+	}
+}
+
+func handleSpeed(calls *core.Calls, player *utils.Player, queue *utils.QueueDB) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		args := strings.Fields(m.Args())
+		speed := 0.0
+		if len(args) > 0 {
+			if n, err := strconv.ParseFloat(strings.TrimSuffix(args[0], "x"), 64); err == nil {
+				speed = n
+			}
+		}
+		if speed < minSpeed || speed > maxSpeed {
+			_, _ = m.Respond(fmt.Sprintf(
+				"**Usage:** `/speed <%.1f-%.1f>`\n\nGive **1** for normal speed.",
+				minSpeed, maxSpeed,
+			))
+			return nil
+		}
+
+		if que := queue.GetCurrent(m.ChatID()); que != nil && que.IsLive() {
+			_, _ = m.Respond("❌ Speed can't be changed in live streams!")
+			return nil
+		}
+
+		effects := calls.Effects(m.ChatID())
+		effects.Speed = speed
+
+		if err := applyEffects(m, calls, player, effects); err != nil {
+			return nil
+		}
+
+		text := fmt.Sprintf("__Speed set to:__ `%gx`\n__By:__ %s", speed, senderMention(m))
+		if que := queue.GetCurrent(m.ChatID()); que != nil {
+			text += fmt.Sprintf("\n\n__Track time:__ `%s`", speedAdjusted(que.Duration, effects))
+		}
+		_, _ = m.Respond(text)
+		return nil
+	}
+}
+
+// applyEffects stores the new effects, the next stream starts with them
+// A running stream is rebuilt with them right away
+func applyEffects(m *tg.NewMessage, calls *core.Calls, player *utils.Player, effects core.AudioEffects) error {
+	chatID := m.ChatID()
+	previous := calls.Effects(chatID)
+
+	if err := calls.SetEffects(chatID, effects); err != nil {
+		log.Printf(">> Saving effects failed for chat %d: %v", chatID, err)
+		_, _ = m.Respond("❌ Failed to save the effect!")
+		return err
+	}
+	if !calls.IsActive(chatID) {
+		return nil
+	}
+
+	if err := player.Restart(context.Background(), chatID); err != nil {
+		calls.SetEffects(chatID, previous)
+		log.Printf(">> Applying effects failed for chat %d: %v", chatID, err)
+
+		if utils.IsUserException(err) {
+			_, _ = m.Respond("❌ " + err.Error())
+		} else {
+			_, _ = m.Respond("❌ Failed to apply the effect!")
+		}
+		return err
+	}
+	return nil
+}
+
+// speedAdjusted renders a track duration as heard at the chat's playback speed
+func speedAdjusted(duration string, effects core.AudioEffects) string {
+	speed := effects.PlaybackSpeed()
+	secs := utils.MinsToSecs(duration)
+	if speed == 1.0 || secs <= 0 {
+		return duration
+	}
+	return fmt.Sprintf("%s → %s at %gx", duration, utils.SecsToMins(int(float64(secs)/speed)), speed)
+}
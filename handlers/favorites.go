@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/core"
+	"shizumusic/helpers"
+	"shizumusic/utils"
+)
+
+// registerFavoriteCallbacks routes the favorites buttons
+func registerFavoriteCallbacks(db *core.Database, player *utils.Player, queue *utils.QueueDB, pages *utils.Pages) {
+
+	// Data: add_favorite|video_id
+	RegisterCallback("add_favorite", CallbackAnyone, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		return handleAddFavorite(cb, data, queue, db)
+	})
+
+	// Data: myfavs|action|user_id|page|delete
+	RegisterCallback("myfavs", CallbackOwner, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		return handleMyFavorites(cb, data, db, pages)
+	})
+
+	// Data: favsplay|audio|user_id, favsplay|video|user_id or favsplay|close|user_id
+	RegisterCallback("favsplay", CallbackOwner, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		return handlePlayFavorites(cb, data, db, player)
+	})
+
+	// Data: delfavs|all|user_id
+	RegisterCallback("delfavs", CallbackOwner, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		if err := db.DeleteFavorites(cb.GetSenderID()); err != nil {
+			return err
+		}
+		_, _ = cb.Answer("Deleted all favorites!")
+		_, err := cb.Edit("✅ **Deleted all your favorite tracks!**")
+		return err
+	})
+}
+
+func handleAddFavorite(cb *tg.CallbackQuery, data *CallbackData, queue *utils.QueueDB, db *core.Database) error {
+	userID := cb.GetSenderID()
+	videoID := data.Arg(0)
+
+	exists, err := db.GetFavorite(userID, videoID)
+	if err != nil {
+		return err
+	}
+	if exists != nil {
+		_, _ = cb.Answer("Already in your favorites!", &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	title, duration := videoID, "Unknown"
+	if que := queue.GetCurrent(cb.GetChatID()); que != nil && que.VideoID == videoID {
+		title, duration = que.Title, que.Duration
+	} else if info, err := utils.YTube.GetVideoInfo(context.Background(), videoID); err == nil && info != nil {
+		title, duration = info.Title, info.Duration
+	}
+
+	if err := db.AddFavorite(userID, videoID, title, duration); err != nil {
+		return err
+	}
+	_, _ = cb.Answer("Added to your favorites!")
+	return nil
+}
+
+func handleMyFavorites(cb *tg.CallbackQuery, data *CallbackData, db *core.Database, pages *utils.Pages) error {
+	userID := cb.GetSenderID()
+
+	switch data.Arg(0) {
+	case "close":
+		_, _ = cb.Answer("Closed!")
+		_, err := cb.Delete()
+		return err
+
+	case "play":
+		_, _ = cb.Answer("")
+		return editCallbackMarkup(cb, helpers.Buttons.PlayFavsMarkup(userID))
+	}
+
+	favs, err := db.GetFavorites(userID)
+	if err != nil {
+		return err
+	}
+	if len(favs) == 0 {
+		_, _ = cb.Answer("You have no favorite tracks!", &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	current, _ := data.Int(2)
+	page := pageIndex(current, pageStep(data.Arg(0)), pageCount(len(favs), favoritesPerPage))
+	showDelete := data.Arg(3) == "1"
+
+	_, _ = cb.Answer("")
+	return pages.FavoritePage(
+		context.Background(), &callbackPage{cb: cb},
+		favs, userID, callbackMention(cb), page, page*favoritesPerPage, true, showDelete,
+	)
+}
+
+func handlePlayFavorites(cb *tg.CallbackQuery, data *CallbackData, db *core.Database, player *utils.Player) error {
+	if data.Arg(0) == "close" {
+		_, _ = cb.Answer("Closed!")
+		_, err := cb.Delete()
+		return err
+	}
+
+	if cb.IsPrivate() {
+		_, _ = cb.Answer("Favorites can only be played in groups!", &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	favs, err := db.GetFavorites(cb.GetSenderID())
+	if err != nil {
+		return err
+	}
+	if len(favs) == 0 {
+		_, _ = cb.Answer("You have no favorite tracks!", &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	msg, err := cb.GetMessage()
+	if err != nil {
+		return err
+	}
+
+	_, _ = cb.Answer("Playing your favorites ...")
+	video := data.Arg(0) == "video"
+	return player.Playlist(
+		context.Background(), &tgMessage{msg: msg},
+		cb.GetChatID(), cb.GetSenderID(), callbackMention(cb), favs, video,
+	)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("listener_watcher", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		calls, player := svc.Calls, svc.Player
+		queue := svc.Queue
+		pages := newPages(client, db)
+
+		watcher := &listenerWatcher{
+			client: client,
+			db:     db,
+			calls:  calls,
+			player: player,
+			alone:  make(map[int64]aloneState),
+		}
+		go every(svc.Done(), listenerPollInterval, watcher.check)
+
+		client.BotClient.AddMessageHandler("cmd:activevc", func(m *tg.NewMessage) error {
+			return core.SudoOnly(handleActiveVC(client, db, calls, queue, pages))(m)
+		})
+	})
+}
+
+// listenerPollInterval is how often the listeners of every voice chat are counted
+const listenerPollInterval = 15 * time.Second
+
+// defaultAloneTimeout is how long the assistant waits alone when ALONE_TIMEOUT is unset
+const defaultAloneTimeout = 2 * time.Minute
+
+// aloneTimeout returns how long a voice chat without listeners is kept before leaving
+// Set ALONE_TIMEOUT in the environment, in minutes, to change it
+func aloneTimeout() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("ALONE_TIMEOUT")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultAloneTimeout
+}
+
+// aloneState remembers since when a voice chat has no listeners
+type aloneState struct {
+	since  time.Time
+	paused bool // the watcher paused the stream, so it may resume it
+}
+
+// listenerWatcher pauses streams nobody listens to and leaves after a grace period
+type listenerWatcher struct {
+	client *core.Client
+	db     *core.Database
+	calls  *core.Calls
+	player *utils.Player
+
+	alone map[int64]aloneState // only touched by check
+}
+
+func (w *listenerWatcher) check() {
+	ctx := context.Background()
+
+	playing := make(map[int64]bool)
+	for _, vc := range w.db.GetActiveVC() {
+		playing[vc.ChatID] = true
+
+		listeners, err := w.calls.ListenerCount(vc.ChatID)
+		if err != nil {
+			log.Printf(">> Counting listeners failed for chat %d: %v", vc.ChatID, err)
+			continue
+		}
+		w.update(ctx, vc.ChatID, listeners)
+	}
+
+	for chatID := range w.alone {
+		if !playing[chatID] {
+			delete(w.alone, chatID)
+		}
+	}
+}
+
+func (w *listenerWatcher) update(ctx context.Context, chatID int64, listeners int) {
+	state, alone := w.alone[chatID]
+
+	switch {
+	case listeners > 0 && alone:
+		delete(w.alone, chatID)
+		if state.paused && w.calls.IsPaused(chatID) {
+			if err := w.calls.ResumeVC(chatID); err != nil {
+				log.Printf(">> Resuming chat %d failed: %v", chatID, err)
+				return
+			}
+			w.notify(chatID, "**▶️ Resumed**\n\n__Someone joined the voice chat, playback continues.__")
+		}
+
+	case listeners == 0 && !alone:
+		state = aloneState{since: time.Now()}
+		if !w.calls.IsPaused(chatID) {
+			if err := w.calls.PauseVC(chatID); err != nil {
+				log.Printf(">> Pausing chat %d failed: %v", chatID, err)
+			} else {
+				state.paused = true
+			}
+		}
+		w.alone[chatID] = state
+		w.notify(chatID, fmt.Sprintf(
+			"**⏸ Paused**\n\n__Nobody is listening. I will leave in `%d` minutes unless someone joins.__",
+			int(aloneTimeout().Minutes()),
+		))
+
+	case listeners == 0 && time.Since(state.since) >= aloneTimeout():
+		delete(w.alone, chatID)
+		w.notify(chatID, "**⏹ Stream Ended**\n\n__Left the voice chat since nobody was listening.__")
+		if err := w.player.Stop(ctx, chatID); err != nil {
+			log.Printf(">> Leaving empty VC of chat %d failed: %v", chatID, err)
+			return
+		}
+		log.Printf(">> Left empty VC of chat %d", chatID)
+	}
+}
+
+func (w *listenerWatcher) notify(chatID int64, text string) {
+	if _, err := w.client.BotClient.SendMessage(chatID, text); err != nil {
+		log.Printf(">> Listener notice failed for chat %d: %v", chatID, err)
+	}
+}
+
+func handleActiveVC(client *core.Client, db *core.Database, calls *core.Calls, queue *utils.QueueDB, pages *utils.Pages) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		collection := activeVCs(client, db, calls, queue)
+		if len(collection) == 0 {
+			_, _ = m.Reply("No active voice chats!")
+			return nil
+		}
+		return pages.ActiveVCPage(context.Background(), &messagePage{m: m}, collection, 0, 0, false)
+	}
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/core"
+	"shizumusic/helpers"
+	"shizumusic/utils"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                                PAGE ADAPTERS                               */
+/* -------------------------------------------------------------------------- */
+
+// callbackPage lets utils.Pages render into the message behind a callback
+type callbackPage struct {
+	cb *tg.CallbackQuery
+}
+
+func (p *callbackPage) Edit(ctx context.Context, text string, buttons interface{}) error {
+	_, err := p.cb.Edit(text, pageSendOptions(buttons))
+	return err
+}
+
+func (p *callbackPage) Reply(ctx context.Context, text string, buttons interface{}) error {
+	_, err := p.cb.Respond(text, pageSendOptions(buttons))
+	return err
+}
+
+func (p *callbackPage) Delete(ctx context.Context) error {
+	_, err := p.cb.Delete()
+	return err
+}
+
+func (p *callbackPage) GetChatID() int64 {
+	return p.cb.GetChatID()
+}
+
+// messagePage lets utils.Pages answer a command message
+type messagePage struct {
+	m *tg.NewMessage
+}
+
+func (p *messagePage) Edit(ctx context.Context, text string, buttons interface{}) error {
+	_, err := p.m.Edit(text, pageSendOptions(buttons))
+	return err
+}
+
+func (p *messagePage) Reply(ctx context.Context, text string, buttons interface{}) error {
+	_, err := p.m.Reply(text, pageSendOptions(buttons))
+	return err
+}
+
+func (p *messagePage) Delete(ctx context.Context) error {
+	_, err := p.m.Delete()
+	return err
+}
+
+func (p *messagePage) GetChatID() int64 {
+	return p.m.ChatID()
+}
+
+func pageSendOptions(buttons interface{}) *tg.SendOptions {
+	opts := &tg.SendOptions{}
+	if markup, ok := buttons.(tg.ReplyMarkup); ok && markup != nil {
+		opts.ReplyMarkup = markup
+	}
+	return opts
+}
+
+// pageButtons adapts helpers.Buttons to utils.PageButtons
+type pageButtons struct{}
+
+func (pageButtons) SongMarkup(randKey, url string, key int) interface{} {
+	return helpers.Buttons.SongMarkup(randKey, url, strconv.Itoa(key))
+}
+
+func (pageButtons) ActiveVCMarkup(count, page int) interface{} {
+	return helpers.Buttons.ActiveVCMarkup(count, page)
+}
+
+func (pageButtons) AuthUsersMarkup(count, page int, randKey string) interface{} {
+	return helpers.Buttons.AuthUsersMarkup(count, page, randKey)
+}
+
+func (pageButtons) FavoriteMarkup(collection [][]interface{}, userID int64, page, index int, delete bool) (interface{}, string, error) {
+	btns := helpers.Buttons.FavoriteMarkup(int64(len(collection)), userID, page, len(collection) > 1, delete)
+	return btns, "", nil
+}
+
+func (pageButtons) QueueMarkup(count, page int) interface{} {
+	return helpers.Buttons.QueueMarkup(count, page)
+}
+
+// pageDatabase adapts core.Database to utils.PageDatabase
+type pageDatabase struct {
+	db *core.Database
+}
+
+func (p *pageDatabase) GetFavorite(ctx context.Context, userID int64, trackID string) (*utils.FavoriteTrack, error) {
+	fav, err := p.db.GetFavorite(userID, trackID)
+	if err != nil {
+		return nil, err
+	}
+	if fav == nil {
+		return nil, utils.NewUserException("favorite not found")
+	}
+	return &utils.FavoriteTrack{
+		Title:    fav.Title,
+		Duration: fav.Duration,
+		AddDate:  fav.AddDate,
+	}, nil
+}
+
+// newPages builds the page renderer used by list buttons
+func newPages(client *core.Client, db *core.Database) *utils.Pages {
+	return utils.NewPages(pageButtons{}, &pageDatabase{db: db}, &tgPlayClient{client: client})
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                 LIST ROUTES                                */
+/* -------------------------------------------------------------------------- */
+
+// Page sizes used by utils.Pages
+const (
+	queuePageSize    = 5
+	activeVCPageSize = 5
+	authUsersPerPage = 6
+	favoritesPerPage = 5
+)
+
+// songResultTTL is how long the song_dl buttons of a search keep working
+const songResultTTL = 10 * time.Minute
+
+// songResults holds song downloader results behind song_dl buttons,
+// keyed by the random key embedded in those buttons
+var (
+	songResults   = make(map[string]songResult)
+	songResultsMu sync.Mutex
+)
+
+type songResult struct {
+	tracks  []utils.SongCache
+	expires time.Time
+}
+
+// storeSongResults keeps search results for the song_dl buttons and returns their key
+// Expired searches are dropped on the way
+func storeSongResults(tracks []utils.SongCache) string {
+	songResultsMu.Lock()
+	defer songResultsMu.Unlock()
+
+	now := time.Now()
+	for key, result := range songResults {
+		if now.After(result.expires) {
+			delete(songResults, key)
+		}
+	}
+
+	key := strconv.FormatInt(rand.Int63(), 36)
+	songResults[key] = songResult{tracks: tracks, expires: now.Add(songResultTTL)}
+	return key
+}
+
+// songTracks returns the search results behind a key, nil once they expired
+func songTracks(key string) []utils.SongCache {
+	songResultsMu.Lock()
+	defer songResultsMu.Unlock()
+
+	result, ok := songResults[key]
+	if !ok || time.Now().After(result.expires) {
+		delete(songResults, key)
+		return nil
+	}
+	return result.tracks
+}
+
+// registerPageCallbacks routes the list navigation buttons to utils.Pages
+func registerPageCallbacks(client *core.Client, db *core.Database, calls *core.Calls, queue *utils.QueueDB, pages *utils.Pages) {
+
+	// Data: queue|prev|page or queue|next|page
+	RegisterCallback("queue", CallbackAnyone, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		tracks := queue.GetQueue(cb.GetChatID())
+		if len(tracks) == 0 {
+			_, _ = cb.Answer("Queue is empty!")
+			_, err := cb.Edit(helpers.TextTemplates.QueueEmpty(), &tg.SendOptions{ReplyMarkup: helpers.Buttons.CloseMarkup()})
+			return err
+		}
+
+		current, _ := data.Int(1)
+		page := pageIndex(current, pageStep(data.Arg(0)), pageCount(len(tracks), queuePageSize))
+		_, _ = cb.Answer("")
+		return pages.QueuePage(context.Background(), &callbackPage{cb: cb}, tracks, page, page*queuePageSize, true)
+	})
+
+	// Data: activevc|prev|page or activevc|next|page
+	RegisterCallback("activevc", CallbackSudo, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		collection := activeVCs(client, db, calls, queue)
+		if len(collection) == 0 {
+			_, _ = cb.Answer("No active voice chats!", &tg.CallbackOptions{Alert: true})
+			return nil
+		}
+
+		current, _ := data.Int(1)
+		page := pageIndex(current, pageStep(data.Arg(0)), pageCount(len(collection), activeVCPageSize))
+		_, _ = cb.Answer("")
+		return pages.ActiveVCPage(context.Background(), &callbackPage{cb: cb}, collection, page, page*activeVCPageSize, true)
+	})
+
+	// Data: authus|prev|page|rand_key, authus|next|... or authus|close|...
+	RegisterCallback("authus", CallbackAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		if data.Arg(0) == "close" {
+			_, _ = cb.Answer("Closed!")
+			_, err := cb.Delete()
+			return err
+		}
+
+		collection, err := authUsers(db, cb.GetChatID())
+		if err != nil {
+			return err
+		}
+		if len(collection) == 0 {
+			_, _ = cb.Answer("No authorized users in this chat!", &tg.CallbackOptions{Alert: true})
+			return nil
+		}
+
+		current, _ := data.Int(1)
+		page := pageIndex(current, pageStep(data.Arg(0)), pageCount(len(collection), authUsersPerPage))
+		_, _ = cb.Answer("")
+		return pages.AuthUsersPage(context.Background(), &callbackPage{cb: cb}, collection, data.Arg(2), page, page*authUsersPerPage, true)
+	})
+
+	// Data: song_dl|action|key|rand_key
+	RegisterCallback("song_dl", CallbackAnyone, func(cb *tg.CallbackQuery, data *CallbackData) error {
+		return handleSongCallback(cb, data, pages)
+	})
+}
+
+func handleSongCallback(cb *tg.CallbackQuery, data *CallbackData, pages *utils.Pages) error {
+	if data.Arg(0) == "close" {
+		_, _ = cb.Answer("Closed!")
+		_, err := cb.Delete()
+		return err
+	}
+
+	randKey := data.Arg(2)
+	tracks := songTracks(randKey)
+
+	key, _ := data.Int(1)
+	if len(tracks) == 0 || key < 0 || key >= len(tracks) {
+		_, _ = cb.Answer("Query timed out, search again!", &tg.CallbackOptions{Alert: true})
+		_, err := cb.Delete()
+		return err
+	}
+
+	ctx := context.Background()
+	switch data.Arg(0) {
+	case "prev", "next":
+		_, _ = cb.Answer("")
+		key = pageIndex(key, pageStep(data.Arg(0)), len(tracks))
+		return pages.SongPage(ctx, &callbackPage{cb: cb}, map[string][]utils.SongCache{randKey: tracks}, randKey, key)
+
+	case "adl", "vdl":
+		video := data.Arg(0) == "vdl"
+		track := tracks[key]
+
+		_, _ = cb.Answer("Downloading ...")
+		_, _ = cb.Edit(fmt.Sprintf("⬇️ Downloading `%s` ...", track.Title))
+
+		file, err := utils.YTube.Download(ctx, track.Link, false, video)
+		if err != nil {
+			_, _ = cb.Edit(fmt.Sprintf("❌ Download failed: %v", err))
+			return nil
+		}
+		defer os.Remove(file)
+
+		if _, err := cb.RespondMedia(file, &tg.MediaOptions{Caption: fmt.Sprintf("**🎵 %s**", track.Title)}); err != nil {
+			_, _ = cb.Edit("❌ Failed to upload the file!")
+			return nil
+		}
+		_, err = cb.Delete()
+		return err
+	}
+
+	_, _ = cb.Answer("Unknown action!", &tg.CallbackOptions{Alert: true})
+	return nil
+}
+
+// activeVCs lists the active voice chats for ActiveVCPage
+// Listener counts are fetched live, falling back to the last poll
+func activeVCs(client *core.Client, db *core.Database, calls *core.Calls, queue *utils.QueueDB) []utils.ActiveVC {
+	pc := &tgPlayClient{client: client}
+
+	var collection []utils.ActiveVC
+	for _, vc := range db.GetActiveVC() {
+		title := "Unknown Chat"
+		if entity, err := pc.GetEntity(context.Background(), vc.ChatID); err == nil {
+			title = entity.Title
+		}
+
+		playing := "Nothing"
+		if que := queue.GetCurrent(vc.ChatID); que != nil {
+			playing = que.Title
+		}
+
+		// The listener watcher keeps the counts fresh, only new chats are counted here
+		listeners, ok := calls.Listeners(vc.ChatID)
+		if !ok {
+			listeners, _ = calls.ListenerCount(vc.ChatID)
+		}
+
+		collection = append(collection, utils.ActiveVC{
+			Title:        title,
+			ChatID:       vc.ChatID,
+			Participants: listeners,
+			Playing:      playing,
+			VCType:       vc.VCType,
+			ActiveSince:  formatUptime(time.Since(vc.JoinTime)),
+		})
+	}
+	return collection
+}
+
+// authUsers lists the authorized users of a chat for AuthUsersPage
+func authUsers(db *core.Database, chatID int64) ([]utils.AuthUser, error) {
+	users, err := db.GetAuthUsers(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := make([]utils.AuthUser, 0, len(users))
+	for _, user := range users {
+		collection = append(collection, utils.AuthUser{
+			AuthUser:  fmt.Sprintf("[%s](tg://user?id=%d)", user.UserName, user.UserID),
+			AdminName: user.AdminName,
+			AdminID:   user.AdminID,
+			AuthDate:  user.AuthDate,
+		})
+	}
+	return collection, nil
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/core"
+	"shizumusic/helpers"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("queue_persistence", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		calls, player := svc.Calls, svc.Player
+
+		writer := &stateWriter{db: db, calls: calls, queue: svc.Queue}
+		stateWriterMu.Lock()
+		activeStateWriter = writer
+		stateWriterMu.Unlock()
+
+		go every(svc.Done(), stateFlushInterval, writer.flush)
+		go restoreQueues(client, db, calls, player)
+
+		// Data: resume|play|chat_id or resume|drop|chat_id
+		RegisterCallback("resume", CallbackChatAuth, func(cb *tg.CallbackQuery, data *CallbackData) error {
+			return handleResumeCallback(cb, data, player)
+		})
+	})
+}
+
+// stateFlushInterval is how often changed queues are written to the database
+const stateFlushInterval = 10 * time.Second
+
+var (
+	activeStateWriter *stateWriter
+	stateWriterMu     sync.Mutex
+)
+
+// FlushPlaybackState writes every pending queue change to the database
+// Called on shutdown so the latest positions survive the restart
+func FlushPlaybackState() {
+	stateWriterMu.Lock()
+	writer := activeStateWriter
+	stateWriterMu.Unlock()
+
+	if writer != nil {
+		writer.flush()
+	}
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                WRITE BEHIND                                */
+/* -------------------------------------------------------------------------- */
+
+// stateWriter periodically saves the queues and player state that changed
+type stateWriter struct {
+	db    *core.Database
+	calls *core.Calls
+	queue *utils.QueueDB
+	mu    sync.Mutex
+}
+
+// flush saves every chat whose state changed, plus every playing chat
+// since the position of its current track keeps moving
+func (w *stateWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	chats := make(map[int64]bool)
+	for _, chatID := range w.queue.DirtyChats() {
+		chats[chatID] = true
+	}
+	for _, chatID := range w.db.DirtyChats() {
+		chats[chatID] = true
+	}
+	for _, vc := range w.db.GetActiveVC() {
+		chats[vc.ChatID] = true
+	}
+
+	for chatID := range chats {
+		if err := w.save(chatID); err != nil {
+			log.Printf(">> Saving playback state failed for chat %d: %v", chatID, err)
+		}
+	}
+}
+
+func (w *stateWriter) save(chatID int64) error {
+	queue := w.queue.GetQueue(chatID)
+	if len(queue) == 0 {
+		return w.db.DeletePlaybackState(chatID)
+	}
+
+	tracks := make([]core.QueueTrack, 0, len(queue))
+	for _, item := range queue {
+		tracks = append(tracks, core.QueueTrack{
+			UserID:   item.UserID,
+			Duration: item.Duration,
+			File:     item.File,
+			Title:    item.Title,
+			User:     item.User,
+			VideoID:  item.VideoID,
+			VCType:   item.VCType,
+			Played:   item.Played,
+		})
+	}
+	if w.calls.IsActive(chatID) {
+		tracks[0].Played = w.calls.Position(chatID)
+	}
+
+	loop, _ := w.db.GetLoop(chatID)
+	active, _ := w.db.IsActiveVC(chatID)
+
+	return w.db.SavePlaybackState(core.PlaybackState{
+		ChatID:  chatID,
+		Tracks:  tracks,
+		Loop:    loop,
+		Effects: w.db.GetAudioEffects(chatID),
+		Active:  active,
+	})
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                   RESTORE                                  */
+/* -------------------------------------------------------------------------- */
+
+// restoreQueues loads the saved queues on boot and offers each chat to resume
+func restoreQueues(client *core.Client, db *core.Database, calls *core.Calls, player *utils.Player) {
+	states, err := db.GetPlaybackStates()
+	if err != nil {
+		log.Printf(">> Loading saved queues failed: %v", err)
+		return
+	}
+
+	ctx := context.Background()
+	pc := &tgPlayClient{client: client}
+
+	restored := 0
+	for _, state := range states {
+		// Only chats that were streaming when the bot went down get an offer
+		if len(state.Tracks) == 0 || !state.Active {
+			db.DeletePlaybackState(state.ChatID)
+			continue
+		}
+
+		items := make([]utils.QueueItem, 0, len(state.Tracks))
+		for _, track := range state.Tracks {
+			items = append(items, utils.QueueItem{
+				ChatID:   state.ChatID,
+				UserID:   track.UserID,
+				Duration: track.Duration,
+				File:     track.File,
+				Title:    track.Title,
+				User:     track.User,
+				VideoID:  track.VideoID,
+				VCType:   track.VCType,
+				Played:   track.Played,
+			})
+		}
+
+		player.RestoreQueue(state.ChatID, items)
+		db.SetLoop(state.ChatID, state.Loop)
+		db.SetAudioEffects(state.ChatID, state.Effects.BassBoost, state.Effects.PlaybackSpeed())
+
+		current := items[0]
+		text := fmt.Sprintf(
+			helpers.TextTemplates.ResumeOffer(),
+			current.Title,
+			utils.SecsToMins(current.Played),
+			len(items)-1,
+		)
+		if err := pc.SendMessage(ctx, state.ChatID, text, helpers.Buttons.ResumeMarkup(state.ChatID)); err != nil {
+			// The bot can no longer talk there, nobody could resume it
+			log.Printf(">> Resume offer failed for chat %d: %v", state.ChatID, err)
+			player.Discard(ctx, state.ChatID)
+			continue
+		}
+		restored++
+	}
+
+	if restored > 0 {
+		log.Printf(">> Restored %d saved queues", restored)
+	}
+}
+
+// handleResumeCallback resumes or discards a restored queue
+// Data: resume|play|chat_id or resume|drop|chat_id
+func handleResumeCallback(cb *tg.CallbackQuery, data *CallbackData, player *utils.Player) error {
+	chatID, err := data.Int64(1)
+	if err != nil {
+		_, _ = cb.Answer("Invalid callback data!", &tg.CallbackOptions{Alert: true})
+		return nil
+	}
+
+	ctx := context.Background()
+	switch data.Arg(0) {
+	case "play":
+		_, _ = cb.Answer("Resuming ...")
+		_, _ = cb.Edit("♻️ Resuming ...")
+
+		if err := player.ResumeQueue(ctx, chatID); err != nil {
+			if utils.IsUserException(err) {
+				_, _ = cb.Edit("❌ " + err.Error())
+				return nil
+			}
+			_, _ = cb.Edit(fmt.Sprintf("❌ Failed to resume: %v", err))
+			return nil
+		}
+		_, err = cb.Delete()
+		return err
+
+	case "drop":
+		if err := player.Discard(ctx, chatID); err != nil {
+			_, _ = cb.Answer(err.Error(), &tg.CallbackOptions{Alert: true})
+			return nil
+		}
+		_, _ = cb.Answer("Discarded!")
+		_, err = cb.Edit(fmt.Sprintf("__Saved queue discarded by:__ %s", callbackMention(cb)))
+		return err
+	}
+
+	_, _ = cb.Answer("Unknown action!", &tg.CallbackOptions{Alert: true})
+	return nil
+}
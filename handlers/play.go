@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strings"
 
 	tg "github.com/amarnathcjd/gogram/telegram"
@@ -13,20 +14,10 @@ import (
 )
 
 func init() {
-	RegisterPlugin("play_commands", func(client *core.Client, db *core.Database) {
-
-		// Use already initialized calls if available
-		calls := core.NewCalls(client.UserClient)
-		adapter := core.NewVCAdapter(calls)
-
-		player := utils.NewPlayer(
-			adapter,
-			utils.YTube,
-			utils.Thumb,
-			db,
-			nil,
-			utils.Queue,
-		)
+	RegisterPlugin("play_commands", func(svc *Services) {
+
+		client := svc.Client
+		calls, player, queue := svc.Calls, svc.Player, svc.Queue
 
 		client.BotClient.AddMessageHandler("/play", func(m *tg.NewMessage) error {
 			return handlePlay(m, client, player, false, false)
@@ -44,9 +35,19 @@ func init() {
 			return handlePlay(m, client, player, true, true)
 		})
 
-		client.BotClient.AddMessageHandler("/queue", handleQueue)
+		client.BotClient.AddMessageHandler("cmd:stream", func(m *tg.NewMessage) error {
+			return handleStream(m, player, false)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:vstream", func(m *tg.NewMessage) error {
+			return handleStream(m, player, true)
+		})
+
+		client.BotClient.AddMessageHandler("/queue", func(m *tg.NewMessage) error {
+			return handleQueue(m, queue)
+		})
 		client.BotClient.AddMessageHandler("/current", func(m *tg.NewMessage) error {
-			return handleCurrent(m, client)
+			return handleCurrent(m, client, calls, player, queue)
 		})
 	})
 }
@@ -83,6 +84,65 @@ func (t *tgMessage) Delete(ctx context.Context) error {
 	return err
 }
 
+/* -------------------------------------------------------------------------- */
+/*                               CLIENT WRAPPER                               */
+/* -------------------------------------------------------------------------- */
+
+type tgPlayClient struct {
+	client *core.Client
+}
+
+func (t *tgPlayClient) SendMessage(ctx context.Context, chatID int64, text string, buttons interface{}) error {
+	opts := &tg.SendOptions{}
+	if markup, ok := buttons.(tg.ReplyMarkup); ok {
+		opts.ReplyMarkup = markup
+	}
+	_, err := t.client.BotClient.SendMessage(chatID, text, opts)
+	return err
+}
+
+func (t *tgPlayClient) GetEntity(ctx context.Context, chatID int64) (*utils.ChatEntity, error) {
+	peer, err := t.client.BotClient.GetPeer(chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := peer.(type) {
+	case *tg.ChatObj:
+		return &utils.ChatEntity{ID: chatID, Title: p.Title, Type: "group"}, nil
+	case *tg.Channel:
+		chatType := "supergroup"
+		if p.Broadcast {
+			chatType = "channel"
+		}
+		return &utils.ChatEntity{ID: chatID, Title: p.Title, Type: chatType}, nil
+	case *tg.UserObj:
+		return &utils.ChatEntity{ID: chatID, Title: p.FirstName, Type: "user"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported peer type: %T", peer)
+	}
+}
+
+func (t *tgPlayClient) GetBotUsername() string {
+	me, err := t.client.BotClient.GetMe()
+	if err != nil || me == nil {
+		return ""
+	}
+	return me.Username
+}
+
+func (t *tgPlayClient) GetBotMention() string {
+	return fmt.Sprintf("@%s", t.GetBotUsername())
+}
+
+func (t *tgPlayClient) DeleteMessage(ctx context.Context, message interface{}) error {
+	if msg, ok := message.(*tg.NewMessage); ok {
+		_, err := msg.Delete()
+		return err
+	}
+	return nil
+}
+
 /* -------------------------------------------------------------------------- */
 /*                                 PLAY LOGIC                                 */
 /* -------------------------------------------------------------------------- */
@@ -108,9 +168,19 @@ func handlePlay(
 		return nil
 	}
 
+	/* ---------------------------- TELEGRAM MEDIA ----------------------------- */
+
+	if m.ReplyToMsgID() != 0 {
+		if reply, err := m.GetReplyMessage(); err == nil {
+			if media := core.GetTGMedia(reply); media != nil {
+				return handleTGPlay(m, sender, player, media, video, force)
+			}
+		}
+	}
+
 	parts := strings.SplitN(m.Text(), " ", 2)
 	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
-		_, _ = m.Reply("**Usage:** `/play <song name or YouTube URL>`")
+		_, _ = m.Reply("**Usage:** `/play <song name or YouTube URL>`\n\nOr reply to an audio or video file.")
 		return nil
 	}
 
@@ -136,6 +206,16 @@ func handlePlay(
 		vcType = "video"
 	}
 
+	/* ---------------------------- YOUTUBE PLAYLIST ---------------------------- */
+
+	if utils.IsPlaylistURL(query) {
+		err := player.ImportPlaylist(ctx, msgWrapper, m.ChatID(), sender.ID, mention, query, video)
+		if utils.IsUserException(err) {
+			_ = msgWrapper.Edit(ctx, "❌ "+err.Error())
+		}
+		return nil
+	}
+
 	/* --------------------------- YOUTUBE DIRECT LINK -------------------------- */
 
 	if strings.Contains(query, "youtube.com") ||
@@ -193,11 +273,118 @@ func handlePlay(
 	return player.Play(ctx, msgWrapper, playCtx, true)
 }
 
+// handleTGPlay downloads a replied Telegram file and queues it
+func handleTGPlay(
+	m *tg.NewMessage,
+	sender *tg.UserObj,
+	player *utils.Player,
+	media *core.TGMedia,
+	video bool,
+	force bool,
+) error {
+
+	dlMsg, err := m.Reply("⬇️ Downloading ...")
+	if err != nil {
+		return nil
+	}
+
+	msgWrapper := &tgMessage{msg: dlMsg}
+	ctx := context.Background()
+
+	filePath, err := media.Download(ctx, config.Cfg.DwlDir, func(percent float64) {
+		_ = msgWrapper.Edit(ctx, fmt.Sprintf("⬇️ Downloading `%s` ... `%.0f%%`", media.Title, percent))
+	})
+	if err != nil {
+		_ = msgWrapper.Edit(ctx, fmt.Sprintf("❌ Download failed: %v", err))
+		return nil
+	}
+
+	duration := media.Duration
+	if duration <= 0 {
+		duration = core.ProbeDuration(ctx, filePath)
+	}
+
+	// Audio files always play as voice, even through /vplay
+	vcType := "voice"
+	if video && media.Video {
+		vcType = "video"
+	}
+
+	playCtx := utils.PlayContext{
+		ChatID:   m.ChatID(),
+		UserID:   sender.ID,
+		Duration: utils.SecsToMins(duration),
+		File:     filePath,
+		Title:    media.Title,
+		User:     fmt.Sprintf("[%s](tg://user?id=%d)", sender.FirstName, sender.ID),
+		VideoID:  "telegram",
+		VCType:   vcType,
+		Force:    force,
+	}
+
+	return player.Play(ctx, msgWrapper, playCtx, true)
+}
+
+// handleStream queues a live stream or radio URL without downloading it
+func handleStream(m *tg.NewMessage, player *utils.Player, video bool) error {
+
+	sender, err := m.GetSender()
+	if err != nil || sender == nil {
+		return nil
+	}
+
+	if config.Cfg.IsBanned(sender.ID) || !m.IsGroup() {
+		return nil
+	}
+
+	link := strings.TrimSpace(m.Args())
+	if !isHTTPLink(link) {
+		_, _ = m.Reply("**Usage:** `/stream <url>`\n\n" +
+			"Works with m3u8 (HLS) streams, internet radio and YouTube live streams.")
+		return nil
+	}
+
+	streamMsg, err := m.Reply("📡 Connecting to stream ...")
+	if err != nil {
+		return nil
+	}
+
+	msgWrapper := &tgMessage{msg: streamMsg}
+	ctx := context.Background()
+
+	parsed, _ := url.Parse(link)
+	title := parsed.Host
+	if videoID := utils.ExtractVideoIDFromLink(link); videoID != "" &&
+		(strings.Contains(link, "youtube.com") || strings.Contains(link, "youtu.be")) {
+		if info, err := utils.YTube.GetVideoInfo(ctx, videoID); err == nil && info != nil {
+			title = info.Title
+		}
+	}
+
+	vcType := "voice"
+	if video {
+		vcType = "video"
+	}
+
+	playCtx := utils.PlayContext{
+		ChatID:   m.ChatID(),
+		UserID:   sender.ID,
+		Duration: "LIVE",
+		File:     link,
+		Title:    title,
+		User:     fmt.Sprintf("[%s](tg://user?id=%d)", sender.FirstName, sender.ID),
+		VideoID:  "live",
+		VCType:   vcType,
+	}
+
+	return player.Play(ctx, msgWrapper, playCtx, true)
+}
+
 /* -------------------------------------------------------------------------- */
 /*                                 QUEUE LOGIC                                */
 /* -------------------------------------------------------------------------- */
 
-func handleQueue(m *tg.NewMessage) error {
+func handleQueue(m *tg.NewMessage, queue *utils.QueueDB) error {
 
 	sender, err := m.GetSender()
 	if err != nil || sender == nil {
@@ -212,9 +399,9 @@ func handleQueue(m *tg.NewMessage) error {
 		return nil
 	}
 
-	queue := utils.Queue.GetQueue(m.ChatID())
+	tracks := queue.GetQueue(m.ChatID())
 
-	if len(queue) == 0 {
+	if len(tracks) == 0 {
 		btns := helpers.Buttons.CloseMarkup()
 		_, _ = m.Reply(
 			helpers.TextTemplates.QueueEmpty(),
@@ -225,7 +412,7 @@ func handleQueue(m *tg.NewMessage) error {
 
 	text := "╭─────────────────────╮\n│  **📋 Queue**\n╰─────────────────────╯\n\n"
 
-	for i, item := range queue {
+	for i, item := range tracks {
 		if i == 0 {
 			text += fmt.Sprintf(
 				"**▶️ Now:** `%s` | `%s`\n\n**📌 Up Next:**\n",
@@ -243,7 +430,7 @@ func handleQueue(m *tg.NewMessage) error {
 		}
 	}
 
-	btns := helpers.Buttons.QueueMarkup(len(queue), 0)
+	btns := helpers.Buttons.QueueMarkup(len(tracks), 0)
 	_, _ = m.Reply(text, &tg.SendOptions{ReplyMarkup: btns})
 	return nil
 }
@@ -252,7 +439,7 @@ func handleQueue(m *tg.NewMessage) error {
 /*                               CURRENT PLAYING                              */
 /* -------------------------------------------------------------------------- */
 
-func handleCurrent(m *tg.NewMessage, client *core.Client) error {
+func handleCurrent(m *tg.NewMessage, client *core.Client, calls *core.Calls, player *utils.Player, queue *utils.QueueDB) error {
 
 	sender, err := m.GetSender()
 	if err != nil || sender == nil {
@@ -267,8 +454,10 @@ func handleCurrent(m *tg.NewMessage, client *core.Client) error {
 		return nil
 	}
 
-	que := utils.Queue.GetCurrent(m.ChatID())
-	if que == nil {
+	// Only a joined player or a restored queue waiting to resume has a current track,
+	// anything else left in the queue is not playing
+	que := queue.GetCurrent(m.ChatID())
+	if que == nil || (!calls.IsActive(m.ChatID()) && !player.IsPending(m.ChatID())) {
 		btns := helpers.Buttons.CloseMarkup()
 		_, _ = m.Reply(
 			helpers.TextTemplates.NothingPlaying(),
@@ -288,9 +477,10 @@ func handleCurrent(m *tg.NewMessage, client *core.Client) error {
 		helpers.TextTemplates.Playing(),
 		fmt.Sprintf("@%s", me.Username),
 		que.Title,
-		que.Duration,
+		speedAdjusted(que.Duration, calls.Effects(m.ChatID())),
 		que.User,
 	)
+	text += "\n\n" + playbackProgress(calls, m.ChatID(), que)
 
 	photo := utils.Thumb.Generate(359, 297, que.VideoID)
 
@@ -301,3 +491,22 @@ func handleCurrent(m *tg.NewMessage, client *core.Client) error {
 
 	return nil
 }
+
+// playbackProgress renders the progress bar of the current track
+func playbackProgress(calls *core.Calls, chatID int64, que *utils.QueueItem) string {
+	if que.IsLive() {
+		return "**🔴 LIVE**"
+	}
+
+	// A restored queue that wasn't resumed yet only knows its saved position
+	played := que.Played
+	if calls.IsActive(chatID) {
+		played = calls.Position(chatID)
+	}
+
+	bar := fmt.Sprintf("`%s`", utils.ProgressBar(played, utils.MinsToSecs(que.Duration)))
+	if calls.IsPaused(chatID) {
+		bar = "⏸ " + bar
+	}
+	return bar
+}
@@ -2,35 +2,33 @@ package handlers
 
 import (
 	"log"
-	
-	"shizumusic/core"
 )
 
 // PluginRegistration holds plugin registration info
 type PluginRegistration struct {
 	Name     string
-	Register func(client *core.Client, db *core.Database)
+	Register func(svc *Services)
 }
 
 // Global plugin registry
 var pluginRegistry []PluginRegistration
 
 // RegisterPlugin registers a new plugin
-func RegisterPlugin(name string, register func(client *core.Client, db *core.Database)) {
+func RegisterPlugin(name string, register func(svc *Services)) {
 	pluginRegistry = append(pluginRegistry, PluginRegistration{
 		Name:     name,
 		Register: register,
 	})
 }
 
-// LoadAllPlugins loads all registered plugins
-func LoadAllPlugins(client *core.Client, db *core.Database) {
+// LoadAllPlugins loads all registered plugins, every one sharing the same services
+func LoadAllPlugins(svc *Services) {
 	log.Println(">> Loading plugins...")
 	
 	loadedCount := 0
 	for _, plugin := range pluginRegistry {
 		log.Printf("   ✅ Loading: %s", plugin.Name)
-		plugin.Register(client, db)
+		plugin.Register(svc)
 		loadedCount++
 	}
 	
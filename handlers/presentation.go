@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("presentation_commands", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		calls, player := svc.Calls, svc.Player
+
+		// Shared files are deleted once they are no longer presented
+		calls.OnPresentationEnd(func(chatID int64, filePath string) {
+			player.CleanupTracks([]utils.QueueItem{{File: filePath}})
+		})
+
+		client.BotClient.AddMessageHandler("cmd:present", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handlePresent(calls, player))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:stoppresent", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleStopPresent(calls, player))(m)
+		})
+	})
+}
+
+// handlePresent shares a video as presentation next to the playing track
+// The video comes from a replied Telegram video or a link
+func handlePresent(calls *core.Calls, player *utils.Player) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		chatID := m.ChatID()
+		if !calls.IsActive(chatID) {
+			_, _ = m.Respond("**❌ No Active Stream**\n\n" +
+				"Play something first, the presentation runs next to it!")
+			return nil
+		}
+
+		reply, _ := m.GetReplyMessage()
+		media := core.GetTGMedia(reply)
+		link := strings.TrimSpace(m.Args())
+		if (media == nil || !media.Video) && !isHTTPLink(link) {
+			_, _ = m.Respond("**Usage:**\n" +
+				"__- Present a video >__ reply to it with `/present`\n" +
+				"__- Present a link >__ `/present <url>`\n\n" +
+				"Use /stoppresent to end the presentation.")
+			return nil
+		}
+
+		status, err := m.Respond("⬇️ Preparing the presentation ...")
+		if err != nil {
+			return nil
+		}
+		msgWrapper := &tgMessage{msg: status}
+		ctx := context.Background()
+
+		source, title, err := presentationSource(ctx, msgWrapper, media, link)
+		if err != nil {
+			_ = msgWrapper.Edit(ctx, fmt.Sprintf("❌ Failed to prepare the presentation: %v", err))
+			return nil
+		}
+
+		previous := calls.Presentation(chatID)
+		if err := calls.StartPresentation(chatID, source); err != nil {
+			log.Printf(">> Starting presentation failed for chat %d: %v", chatID, err)
+			_ = msgWrapper.Edit(ctx, "❌ Failed to start the presentation!")
+			return nil
+		}
+		if err := player.Restart(ctx, chatID); err != nil {
+			calls.StopPresentation(chatID)
+			log.Printf(">> Starting presentation failed for chat %d: %v", chatID, err)
+			_ = msgWrapper.Edit(ctx, "❌ Failed to start the presentation!")
+			return nil
+		}
+		if previous != "" && previous != source {
+			player.CleanupTracks([]utils.QueueItem{{File: previous}})
+		}
+
+		_ = msgWrapper.Edit(ctx, fmt.Sprintf(
+			"__Presenting:__ `%s`\n__By:__ %s\n\nUse /stoppresent to end it.",
+			title, senderMention(m),
+		))
+		return nil
+	}
+}
+
+func handleStopPresent(calls *core.Calls, player *utils.Player) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		chatID := m.ChatID()
+		if calls.StopPresentation(chatID) != nil {
+			_, _ = m.Respond("❌ Nothing is being presented right now!")
+			return nil
+		}
+
+		// Rebuild the sources so the shared video stops reading
+		if err := player.Restart(context.Background(), chatID); err != nil {
+			log.Printf(">> Rebuilding stream after presentation failed for chat %d: %v", chatID, err)
+		}
+		_, _ = m.Respond(fmt.Sprintf("__Presentation stopped by:__ %s", senderMention(m)))
+		return nil
+	}
+}
+
+// presentationSource returns the file or URL to present and its title
+// YouTube links are downloaded, other links are read directly by ffmpeg
+func presentationSource(ctx context.Context, msg *tgMessage, media *core.TGMedia, link string) (string, string, error) {
+	if media != nil && media.Video {
+		filePath, err := media.Download(ctx, config.Cfg.DwlDir, func(percent float64) {
+			_ = msg.Edit(ctx, fmt.Sprintf("⬇️ Downloading `%s` ... `%.0f%%`", media.Title, percent))
+		})
+		return filePath, media.Title, err
+	}
+
+	if !strings.Contains(link, "youtube.com") && !strings.Contains(link, "youtu.be") {
+		parsed, _ := url.Parse(link)
+		return link, parsed.Host, nil
+	}
+
+	title := "YouTube Video"
+	if info, err := utils.YTube.GetVideoInfo(ctx, utils.ExtractVideoIDFromLink(link)); err == nil && info != nil {
+		title = info.Title
+	}
+	_ = msg.Edit(ctx, fmt.Sprintf("⬇️ Downloading `%s` ...", title))
+
+	filePath, err := utils.YTube.Download(ctx, link, false, true)
+	return filePath, title, err
+}
+
+// isHTTPLink reports whether text is an http or https URL
+func isHTTPLink(text string) bool {
+	parsed, err := url.Parse(text)
+	return text != "" && err == nil && parsed.Host != "" &&
+		(parsed.Scheme == "http" || parsed.Scheme == "https")
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("quality_commands", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		calls, player := svc.Calls, svc.Player
+
+		client.BotClient.AddMessageHandler("cmd:quality", func(m *tg.NewMessage) error {
+			return core.AdminOnly(handleQuality(calls, player, db))(m)
+		})
+	})
+}
+
+func handleQuality(calls *core.Calls, player *utils.Player, db *core.Database) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		chatID := m.ChatID()
+		current := db.GetStreamQuality(chatID)
+
+		args := strings.Fields(m.Args())
+		if len(args) == 0 {
+			_, _ = m.Respond(qualityUsage(current))
+			return nil
+		}
+
+		quality, ok := core.QualityPreset(args[0])
+		if !ok {
+			if strings.ToLower(args[0]) != core.QualityCustom {
+				_, _ = m.Respond(qualityUsage(current))
+				return nil
+			}
+			custom, err := parseCustomQuality(current, args[1:])
+			if err != nil {
+				_, _ = m.Respond(fmt.Sprintf("❌ Invalid custom quality: %v\n\n**Usage:** `/quality custom <width>x<height> <fps> [bitrate kbps]`", err))
+				return nil
+			}
+			quality = custom
+		}
+
+		if err := applyQuality(m, player, db, calls, current, quality); err != nil {
+			return nil
+		}
+
+		_, _ = m.Respond(fmt.Sprintf(
+			"__Stream quality set to:__ `%s`\n`%s`\n__By:__ %s",
+			quality.Preset, quality, senderMention(m),
+		))
+		return nil
+	}
+}
+
+// applyQuality saves the new quality, the next stream starts with it
+// A running stream is rebuilt with it right away
+// The call is kept, only its stream sources are swapped
+func applyQuality(m *tg.NewMessage, player *utils.Player, db *core.Database, calls *core.Calls, previous, quality core.StreamQuality) error {
+	chatID := m.ChatID()
+
+	if err := db.SetStreamQuality(chatID, quality); err != nil {
+		log.Printf(">> Saving stream quality failed for chat %d: %v", chatID, err)
+		_, _ = m.Respond("❌ Failed to save the stream quality!")
+		return err
+	}
+	if !calls.IsActive(chatID) {
+		return nil
+	}
+
+	if err := player.Restart(context.Background(), chatID); err != nil {
+		db.SetStreamQuality(chatID, previous)
+		log.Printf(">> Applying stream quality failed for chat %d: %v", chatID, err)
+
+		if utils.IsUserException(err) {
+			_, _ = m.Respond("❌ " + err.Error())
+		} else {
+			_, _ = m.Respond("❌ Failed to apply the stream quality!")
+		}
+		return err
+	}
+	return nil
+}
+
+// parseCustomQuality parses "<width>x<height> <fps> [bitrate]"
+func parseCustomQuality(base core.StreamQuality, args []string) (core.StreamQuality, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return core.StreamQuality{}, fmt.Errorf("give a resolution, fps and an optional bitrate")
+	}
+
+	w, h, found := strings.Cut(strings.ToLower(args[0]), "x")
+	width, err1 := strconv.Atoi(w)
+	height, err2 := strconv.Atoi(h)
+	if !found || err1 != nil || err2 != nil {
+		return core.StreamQuality{}, fmt.Errorf("invalid resolution `%s`", args[0])
+	}
+
+	fps, err := strconv.Atoi(args[1])
+	if err != nil {
+		return core.StreamQuality{}, fmt.Errorf("invalid fps `%s`", args[1])
+	}
+
+	bitrate := 0
+	if len(args) > 2 {
+		bitrate, err = strconv.Atoi(strings.TrimSuffix(strings.ToLower(args[2]), "k"))
+		if err != nil {
+			return core.StreamQuality{}, fmt.Errorf("invalid bitrate `%s`", args[2])
+		}
+	}
+
+	return core.CustomQuality(base, width, height, fps, bitrate)
+}
+
+// qualityUsage shows the current quality and every choice
+func qualityUsage(current core.StreamQuality) string {
+	text := fmt.Sprintf("**🎚 Stream Quality:** `%s`\n`%s`\n\n**Presets:**\n", current.Preset, current)
+	for _, name := range core.QualityPresets {
+		preset, _ := core.QualityPreset(name)
+		text += fmt.Sprintf("__- %s >__ `%s`\n", name, preset)
+	}
+	text += "\n**Usage:**\n" +
+		"__- Pick a preset >__ `/quality high`\n" +
+		"__- Custom video >__ `/quality custom 1280x720 30 1500`"
+	return text
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("queue_commands", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		player, queue := svc.Player, svc.Queue
+
+		client.BotClient.AddMessageHandler("cmd:shuffle", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleShuffle(queue))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:move", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleMove(queue))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:remove", func(m *tg.NewMessage) error {
+			return handleRemove(m, db, player, queue)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:clearqueue", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleClearQueue(player, queue))(m)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:dedupe", func(m *tg.NewMessage) error {
+			return core.AuthOnly(db)(handleDedupe(player, queue))(m)
+		})
+	})
+}
+
+func handleShuffle(queue *utils.QueueDB) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		shuffled := queue.ShuffleQueue(m.ChatID())
+		if shuffled == 0 {
+			_, _ = m.Respond("❌ Need at least two upcoming tracks to shuffle!")
+			return nil
+		}
+
+		_, _ = m.Respond(fmt.Sprintf(
+			"__Queue shuffled by:__ %s\n\n`%d` upcoming tracks reordered.",
+			senderMention(m), shuffled,
+		))
+		return nil
+	}
+}
+
+func handleMove(queue *utils.QueueDB) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		chatID := m.ChatID()
+		args := strings.Fields(m.Args())
+		if len(args) < 2 {
+			_, _ = m.Respond("**Usage:** `/move <from> <to>`\n\nPositions are the numbers shown in /queue.")
+			return nil
+		}
+
+		from, err1 := strconv.Atoi(args[0])
+		to, err2 := strconv.Atoi(args[1])
+		if err1 != nil || err2 != nil || !queue.MoveQueue(chatID, from, to) {
+			_, _ = m.Respond(invalidPositionText(queue.GetQueueLength(chatID)))
+			return nil
+		}
+
+		title := "Track"
+		if tracks := queue.GetQueue(chatID); to < len(tracks) {
+			title = tracks[to].Title
+		}
+		_, _ = m.Respond(fmt.Sprintf(
+			"__Moved__ `%s` __to position__ `#%d`\n__By:__ %s",
+			title, to, senderMention(m),
+		))
+		return nil
+	}
+}
+
+// handleRemove removes upcoming tracks by position or range
+// Users without playback rights may only remove tracks they requested
+func handleRemove(m *tg.NewMessage, db *core.Database, player *utils.Player, queue *utils.QueueDB) error {
+	if !m.IsGroup() || m.Sender == nil || config.Cfg.IsBanned(m.SenderID()) {
+		return nil
+	}
+
+	chatID := m.ChatID()
+	if active, _ := db.IsActiveVC(chatID); !active {
+		_, _ = m.Reply("**❌ No Active Stream**\n\n" +
+			"Nothing is currently playing in the voice chat!")
+		return nil
+	}
+
+	args := strings.Fields(m.Args())
+	if len(args) == 0 {
+		_, _ = m.Reply("**Usage:**\n" +
+			"__- Remove one track >__ `/remove 3`\n" +
+			"__- Remove a range >__ `/remove 2-5`")
+		return nil
+	}
+
+	tracks := queue.GetQueue(chatID)
+	from, to, ok := parseQueueRange(args[0])
+	if !ok || from < 1 || to >= len(tracks) {
+		_, _ = m.Reply(invalidPositionText(len(tracks)))
+		return nil
+	}
+
+	// Ownership is checked by the queue itself, against the tracks it removes
+	owner := m.Sender.ID
+	if core.IsAuthorized(m.Client, db, chatID, m.Sender.ID) {
+		owner = 0
+	}
+
+	removed, foreign := queue.RemoveQueue(chatID, from, to, owner)
+	if foreign > 0 {
+		_, _ = m.Reply(fmt.Sprintf(
+			"❌ Track `#%d` was not requested by you! Only admins can remove tracks of others.", foreign,
+		))
+		return nil
+	}
+	player.CleanupTracks(removed)
+
+	switch len(removed) {
+	case 0:
+		_, _ = m.Reply("❌ Nothing was removed, the queue changed meanwhile!")
+	case 1:
+		_, _ = m.Reply(fmt.Sprintf(
+			"__Removed__ `%s` __from queue__\n__By:__ %s",
+			removed[0].Title, senderMention(m),
+		))
+	default:
+		_, _ = m.Reply(fmt.Sprintf(
+			"__Removed `%d` tracks from queue__\n__By:__ %s",
+			len(removed), senderMention(m),
+		))
+	}
+	return nil
+}
+
+func handleClearQueue(player *utils.Player, queue *utils.QueueDB) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		removed := queue.ClearUpcoming(m.ChatID())
+		if len(removed) == 0 {
+			_, _ = m.Respond("❌ There are no upcoming tracks to clear!")
+			return nil
+		}
+		player.CleanupTracks(removed)
+
+		_, _ = m.Respond(fmt.Sprintf(
+			"__Cleared `%d` upcoming tracks__\n__By:__ %s",
+			len(removed), senderMention(m),
+		))
+		return nil
+	}
+}
+
+func handleDedupe(player *utils.Player, queue *utils.QueueDB) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		removed := queue.DedupeQueue(m.ChatID())
+		if len(removed) == 0 {
+			_, _ = m.Respond("✅ There are no duplicate tracks in queue!")
+			return nil
+		}
+		player.CleanupTracks(removed)
+
+		_, _ = m.Respond(fmt.Sprintf(
+			"__Removed `%d` duplicate tracks__\n__By:__ %s",
+			len(removed), senderMention(m),
+		))
+		return nil
+	}
+}
+
+// parseQueueRange parses "n" or "from-to" into an inclusive range
+func parseQueueRange(arg string) (int, int, bool) {
+	first, last, isRange := strings.Cut(arg, "-")
+
+	from, err := strconv.Atoi(first)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !isRange {
+		return from, from, true
+	}
+
+	to, err := strconv.Atoi(last)
+	if err != nil || to < from {
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+// invalidPositionText explains which queue positions can be used
+func invalidPositionText(queueLength int) string {
+	upcoming := queueLength - 1
+	if upcoming < 1 {
+		return "❌ There are no upcoming tracks in queue!"
+	}
+	return fmt.Sprintf("❌ Invalid position! Give a number between `1` and `%d`, as shown in /queue.", upcoming)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("reconnect_commands", func(svc *Services) {
+
+		client := svc.Client
+		calls, player := svc.Calls, svc.Player
+
+		calls.OnReconnectFailed(func(chatID int64, err error) {
+			reconnectFailed(client, player, chatID, err)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:vclog", func(m *tg.NewMessage) error {
+			return core.SudoOnly(handleVCLog(calls))(m)
+		})
+	})
+}
+
+// reconnectFailed drops the queue of a chat whose call could not be rejoined and tells the chat
+func reconnectFailed(client *core.Client, player *utils.Player, chatID int64, err error) {
+	if stopErr := player.Stop(context.Background(), chatID); stopErr != nil {
+		log.Printf(">> Clearing chat %d after failed reconnect: %v", chatID, stopErr)
+	}
+
+	text := "**📡 Connection Lost**\n\n" +
+		"The voice chat connection dropped and could not be restored, the queue was cleared.\n" +
+		"Use /play to start again."
+	if err != nil {
+		text += fmt.Sprintf("\n\n**Last error:** `%v`", err)
+	}
+	client.BotClient.SendMessage(chatID, text)
+}
+
+func handleVCLog(calls *core.Calls) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		chatID := m.ChatID()
+		if arg := strings.TrimSpace(m.Args()); arg != "" {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				_, _ = m.Reply("**Usage:** `/vclog [chat id]`")
+				return nil
+			}
+			chatID = id
+		}
+
+		history := calls.ConnectionHistory(chatID)
+		if len(history) == 0 {
+			_, _ = m.Reply(fmt.Sprintf("No connection events for `%d`!", chatID))
+			return nil
+		}
+
+		var text strings.Builder
+		text.WriteString(fmt.Sprintf("**📡 Connection Log** `%d`\n\n", chatID))
+		for _, event := range history {
+			state := event.State
+			if event.Presentation {
+				state += " (presentation)"
+			}
+			text.WriteString(fmt.Sprintf("`%s` **%s**", event.Time.UTC().Format("15:04:05"), state))
+			if event.Note != "" {
+				text.WriteString(" - __" + event.Note + "__")
+			}
+			text.WriteString("\n")
+		}
+		if calls.IsReconnecting(chatID) {
+			text.WriteString("\n__Reconnecting now ...__")
+		}
+
+		_, _ = m.Reply(text.String())
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("record_commands", func(svc *Services) {
+
+		client := svc.Client
+		calls := svc.Calls
+
+		calls.OnRecordingEnd(func(rec *core.Recording) {
+			postRecording(client, rec)
+		})
+
+		client.BotClient.AddMessageHandler("cmd:record", func(m *tg.NewMessage) error {
+			return core.AdminOnly(handleRecord(calls))(m)
+		})
+	})
+}
+
+// Defaults for RECORD_MAX_MINUTES and RECORD_MAX_MB
+// Bots can upload up to 50 MB, the size cap leaves room for the last chunk
+const (
+	defaultRecordMinutes = 60
+	defaultRecordMB      = 45
+)
+
+// recordLimits returns the caps of a recording
+// Set RECORD_MAX_MINUTES and RECORD_MAX_MB in the environment to change them
+func recordLimits() core.RecordLimits {
+	minutes, err := strconv.Atoi(os.Getenv("RECORD_MAX_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = defaultRecordMinutes
+	}
+	mb, err := strconv.Atoi(os.Getenv("RECORD_MAX_MB"))
+	if err != nil || mb <= 0 {
+		mb = defaultRecordMB
+	}
+	return core.RecordLimits{
+		MaxSize:     int64(mb) << 20,
+		MaxDuration: time.Duration(minutes) * time.Minute,
+	}
+}
+
+func handleRecord(calls *core.Calls) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if !m.IsGroup() || config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		usage := "**Usage:**\n\n" +
+			"__To start recording:__ `/record start`\n" +
+			"__To stop recording:__ `/record stop`"
+
+		args := strings.Fields(m.Args())
+		if len(args) != 1 {
+			_, _ = m.Respond(usage)
+			return nil
+		}
+
+		chatID := m.ChatID()
+		switch strings.ToLower(args[0]) {
+		case "start":
+			if !calls.IsActive(chatID) {
+				_, _ = m.Respond("**❌ No Active Stream**\n\n" +
+					"The assistant records from the voice chat, play something first!")
+				return nil
+			}
+			if calls.Recording(chatID) != nil {
+				_, _ = m.Respond("❌ This voice chat is already being recorded! Use `/record stop` to end it.")
+				return nil
+			}
+
+			if err := os.MkdirAll(config.Cfg.DwlDir, 0755); err != nil {
+				_, _ = m.Respond(fmt.Sprintf("❌ Failed to start recording: %v", err))
+				return nil
+			}
+			filePath := filepath.Join(config.Cfg.DwlDir, fmt.Sprintf("record_%d_%d.ogg", chatID, time.Now().Unix()))
+
+			limits := recordLimits()
+			if _, err := calls.StartRecording(chatID, filePath, limits); err != nil {
+				log.Printf(">> Starting recording failed for chat %d: %v", chatID, err)
+				_, _ = m.Respond("❌ Failed to start recording!")
+				return nil
+			}
+
+			_, _ = m.Respond(fmt.Sprintf(
+				"🔴 __Recording started by:__ %s\n\n"+
+					"It stops after `%d` minutes or `%d MB`, or with `/record stop`.",
+				senderMention(m), int(limits.MaxDuration.Minutes()), limits.MaxSize>>20,
+			))
+
+		case "stop":
+			if calls.Recording(chatID) == nil {
+				_, _ = m.Respond("❌ This voice chat is not being recorded!")
+				return nil
+			}
+			_, _ = m.Respond(fmt.Sprintf("⏹ __Recording stopped by:__ %s\n\nUploading ...", senderMention(m)))
+			calls.StopRecording(chatID)
+
+		default:
+			_, _ = m.Respond(usage)
+		}
+		return nil
+	}
+}
+
+// postRecording uploads a finished recording to its chat, or to the logger when that fails
+func postRecording(client *core.Client, rec *core.Recording) {
+	defer os.Remove(rec.FilePath)
+
+	duration := int(rec.Duration().Seconds())
+	if rec.Size() == 0 {
+		client.BotClient.SendMessage(rec.ChatID, "❌ The recording is empty, nobody spoke in the voice chat!")
+		return
+	}
+
+	caption := fmt.Sprintf(
+		"**🎙️ Voice Chat Recording**\n\n"+
+			"**⏱️ Duration:** `%s`\n"+
+			"**📅 Started:** `%s`\n"+
+			"**⏹ Ended:** __%s__",
+		utils.SecsToMins(duration), rec.Started.UTC().Format("2006-01-02 15:04 UTC"), rec.Reason,
+	)
+	opts := &tg.MediaOptions{
+		Caption:  caption,
+		FileName: filepath.Base(rec.FilePath),
+		MimeType: "audio/ogg",
+		Attributes: []tg.DocumentAttribute{&tg.DocumentAttributeAudio{
+			Duration: int32(duration),
+			Title:    "Voice Chat Recording",
+		}},
+	}
+
+	_, err := client.BotClient.SendMedia(rec.ChatID, rec.FilePath, opts)
+	if err == nil {
+		return
+	}
+	log.Printf(">> Posting recording failed for chat %d: %v", rec.ChatID, err)
+
+	if config.Cfg.LoggerID == 0 {
+		return
+	}
+	opts.Caption = fmt.Sprintf("%s\n**💬 Chat:** `%d`", caption, rec.ChatID)
+	if _, err := client.BotClient.SendMedia(config.Cfg.LoggerID, rec.FilePath, opts); err != nil {
+		log.Printf(">> Posting recording to logger failed: %v", err)
+	}
+}
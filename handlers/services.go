@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+// Services is the one playback subsystem shared by every plugin
+// main builds it once and hands it to LoadAllPlugins
+type Services struct {
+	Client *core.Client
+	DB     *core.Database
+	Calls  *core.Calls
+	Player *utils.Player
+	Queue  *utils.QueueDB
+
+	// Cancelled by Stop, every background loop of the plugins exits on it
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewServices builds the calls manager and player on top of the started clients
+func NewServices(client *core.Client, db *core.Database) *Services {
+	queue := utils.Queue
+	calls := core.NewCalls(client.Assistants...)
+	calls.SetQualitySource(db.GetStreamQuality)
+	calls.SetEffectsStore(db)
+	calls.SetAssistantStore(db)
+	calls.SetInviter(client.BotClient)
+	calls.SetVCSettingsSource(db.GetVCSettings)
+	db.SetPlayerStates(calls.Players())
+
+	player := utils.NewPlayer(
+		core.NewVCAdapter(calls),
+		utils.YTube,
+		utils.Thumb,
+		db,
+		&tgPlayClient{client: client},
+		queue,
+	)
+
+	// Move on to the next queued track whenever a stream finishes
+	calls.OnStreamEnd(func(chatID int64) {
+		// A call being left or already gone has no queue to move on
+		if !calls.IsActive(chatID) {
+			return
+		}
+		if err := player.ChangeVC(context.Background(), chatID); err != nil {
+			log.Printf(">> Queue progression failed for chat %d: %v", chatID, err)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Services{
+		Client: client,
+		DB:     db,
+		Calls:  calls,
+		Player: player,
+		Queue:  queue,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Done is closed once the services are stopping
+func (s *Services) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Stop tears down the playback subsystem and disconnects the clients
+// Calls end while the bot is still up to upload the recordings they finish,
+// ntgcalls is only freed once the bot stopped and no handler can reach it
+// The database is left for the caller to close
+func (s *Services) Stop() {
+	s.cancel()
+
+	log.Println(">> Saving playback state...")
+	FlushPlaybackState()
+
+	log.Println(">> Stopping NTgCalls...")
+	s.Calls.Stop()
+	s.Client.StopBot()
+	s.Client.StopAssistants()
+	s.Calls.Free()
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/utils"
+)
+
+func init() {
+	RegisterPlugin("song_commands", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		pages := newPages(client, db)
+
+		client.BotClient.AddMessageHandler("cmd:song", func(m *tg.NewMessage) error {
+			return core.UserOnly(handleSong(pages))(m)
+		})
+	})
+}
+
+// songSearchLimit is how many results /song pages through
+const songSearchLimit = 10
+
+// handleSong searches YouTube and opens the song downloader on the results
+// The song_dl buttons of the page download the picked track
+func handleSong(pages *utils.Pages) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		if config.Cfg.IsBanned(m.SenderID()) {
+			return nil
+		}
+
+		query := strings.TrimSpace(m.Args())
+		if query == "" {
+			_, _ = m.Respond("**Usage:** `/song <name or link>`")
+			return nil
+		}
+
+		msg, err := m.Respond(fmt.Sprintf("🔎 Searching `%s` ...", query))
+		if err != nil {
+			return nil
+		}
+
+		ctx := context.Background()
+		videos, err := utils.YTube.GetData(ctx, query, false, songSearchLimit)
+		if err != nil || len(videos) == 0 {
+			_, _ = msg.Edit(fmt.Sprintf("❌ Nothing found for `%s`!", query))
+			return nil
+		}
+
+		tracks := make([]utils.SongCache, 0, len(videos))
+		for _, video := range videos {
+			tracks = append(tracks, utils.SongCache{
+				Title:     video.Title,
+				Link:      video.Link,
+				Thumbnail: video.Thumbnail,
+			})
+		}
+
+		randKey := storeSongResults(tracks)
+		return pages.SongPage(ctx, &messagePage{m: msg}, map[string][]utils.SongCache{randKey: tracks}, randKey, 0)
+	}
+}
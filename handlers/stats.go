@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"log"
+	"time"
+
+	tg "github.com/amarnathcjd/gogram/telegram"
+	"shizumusic/config"
+	"shizumusic/core"
+	"shizumusic/helpers"
+)
+
+func init() {
+	RegisterPlugin("stats_commands", func(svc *Services) {
+
+		client, db := svc.Client, svc.DB
+		calls := svc.Calls
+
+		client.BotClient.AddMessageHandler("cmd:stats", func(m *tg.NewMessage) error {
+			return core.SudoOnly(handleStats(client, db, calls))(m)
+		})
+	})
+}
+
+func handleStats(client *core.Client, db *core.Database, calls *core.Calls) core.HandlerFunc {
+	return func(m *tg.NewMessage) error {
+		msg, err := m.Reply("📊 Collecting stats ...")
+		if err != nil {
+			return nil
+		}
+
+		users, _ := db.TotalUsersCount()
+		chats, _ := db.TotalChatsCount()
+		gbans, _ := db.GetGbannedUsers()
+		blocked, _ := db.GetBlockedUsers()
+		songs, _ := db.TotalSongsCount()
+
+		// Listener counts come from the last poll of the listener watcher
+		active, listeners := 0, 0
+		for _, vc := range db.GetActiveVC() {
+			active++
+			if count, ok := calls.Listeners(vc.ChatID); ok {
+				listeners += count
+			}
+		}
+
+		cpu, disk, ram, cores := "-", "-", "-", 0
+		if system, err := helpers.NewFormatters("UTC").SystemStats(config.Cfg.StartTime); err == nil {
+			cpu, _ = system["cpu"].(string)
+			disk, _ = system["disk"].(string)
+			ram, _ = system["ram"].(string)
+			cores, _ = system["core"].(int)
+		}
+
+		mention := "-"
+		if me, err := client.BotClient.GetMe(); err == nil {
+			mention = "@" + me.Username
+		} else {
+			log.Printf(">> Getting the bot account failed: %v", err)
+		}
+		_, _ = msg.Edit(helpers.MusicUser.GetStatsText(helpers.StatsContext{
+			Users:     int(users),
+			Chats:     int(chats),
+			Gbans:     len(gbans),
+			Blocked:   len(blocked),
+			Songs:     songs,
+			Active:    active,
+			Listeners: listeners,
+			Core:      cores,
+			CPU:       cpu,
+			Disk:      disk,
+			RAM:       ram,
+			Uptime:    formatUptime(time.Since(config.Cfg.StartTime)),
+			Mention:   mention,
+		}))
+		return nil
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -8,8 +9,17 @@ import (
 	tg "github.com/amarnathcjd/gogram/telegram"
 	"shizumusic/config"
 	"shizumusic/core"
+	"shizumusic/utils"
 )
 
+func init() {
+	RegisterPlugin("background_tasks", func(svc *Services) {
+		client, db := svc.Client, svc.DB
+		calls, player := svc.Calls, svc.Player
+		StartBackgroundTasks(svc.Done(), client, db, calls, player)
+	})
+}
+
 // RegisterWatcherHandlers registers event watchers
 func RegisterWatcherHandlers(client *core.Client, db *core.Database) {
 	// Track new users in PM
@@ -163,35 +173,96 @@ func handleResetSpam(client *core.Client, db *core.Database) core.HandlerFunc {
 	}
 }
 
-// StartBackgroundTasks starts background tasks
-func StartBackgroundTasks(db *core.Database, calls *core.Calls) {
-	go func() {
-		for {
-			time.Sleep(1 * time.Second)
-			updatePlayedDuration(db)
-		}
-	}()
+// StartBackgroundTasks starts background tasks, they run until done is closed
+func StartBackgroundTasks(done <-chan struct{}, client *core.Client, db *core.Database, calls *core.Calls, player *utils.Player) {
+	go every(done, time.Second, func() {
+		updatePlayedDuration(db, player)
+	})
 
-	go func() {
-		for {
-			time.Sleep(10 * time.Second)
-			endInactiveVCs(db, calls)
-		}
-	}()
+	go every(done, 10*time.Second, func() {
+		endInactiveVCs(client, db, calls, player)
+	})
 
 	log.Println(">> Background tasks started!")
 }
 
-func updatePlayedDuration(db *core.Database) {
+// every runs task at each interval until done is closed
+func every(done <-chan struct{}, interval time.Duration, task func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			task()
+		}
+	}
+}
+
+// updatePlayedDuration copies the stream position of every playing chat into its queue
+func updatePlayedDuration(db *core.Database, player *utils.Player) {
+	ctx := context.Background()
 	activeVCs := db.GetActiveVC()
 	for _, vc := range activeVCs {
-		if vc.ChatID == 0 {
+		player.SyncPosition(ctx, vc.ChatID)
+	}
+}
+
+// endInactiveVCs leaves voice chats that stayed paused, empty or idle past the autoend timeout
+// The group is told why before the assistant leaves
+func endInactiveVCs(client *core.Client, db *core.Database, calls *core.Calls, player *utils.Player) {
+	activeVCs := db.GetActiveVC()
+
+	enabled, err := db.GetAutoend()
+	if err != nil {
+		log.Printf(">> Reading autoend setting failed: %v", err)
+	}
+	if !enabled {
+		for _, vc := range activeVCs {
+			db.ClearInactive(vc.ChatID)
+		}
+		return
+	}
+
+	ctx := context.Background()
+	timeout := autoendTimeout()
+	for _, vc := range activeVCs {
+		reason := idleReason(calls, vc.ChatID)
+		if reason == "" {
+			db.ClearInactive(vc.ChatID)
+			continue
+		}
+		if since := db.MarkInactive(vc.ChatID); time.Since(since) < timeout {
 			continue
 		}
-		// TODO: utils.Queue.UpdateDuration(vc.ChatID, 1, 1)
+
+		if _, err := client.BotClient.SendMessage(vc.ChatID, fmt.Sprintf(
+			"**⏹ Stream Ended**\n\n__Left the voice chat since %s for `%d` minutes.__",
+			reason, int(timeout.Minutes()),
+		)); err != nil {
+			log.Printf(">> Auto-end notice failed for chat %d: %v", vc.ChatID, err)
+		}
+		if err := player.Stop(ctx, vc.ChatID); err != nil {
+			log.Printf(">> Auto-ending VC of chat %d failed: %v", vc.ChatID, err)
+			db.ClearInactive(vc.ChatID)
+			continue
+		}
+		log.Printf(">> Auto-ended idle VC of chat %d: %s", vc.ChatID, reason)
 	}
 }
 
-func endInactiveVCs(db *core.Database, calls *core.Calls) {
-	// TODO: Check for inactive VCs and end them
+// idleReason explains why a voice chat counts as idle, or returns "" while it is in use
+func idleReason(calls *core.Calls, chatID int64) string {
+	if !calls.IsActive(chatID) {
+		return "nothing was streaming"
+	}
+	if calls.IsPaused(chatID) {
+		return "the stream was paused"
+	}
+	if listeners, ok := calls.Listeners(chatID); ok && listeners == 0 {
+		return "nobody was listening"
+	}
+	return ""
 }
@@ -129,7 +129,7 @@ func (mb *MakeButtons) AuthUsersMarkup(count, page int, randKey string) *tg.Repl
 
 // PlayerMarkup returns player control buttons
 func (mb *MakeButtons) PlayerMarkup(chatID int64, videoID, username string) *tg.ReplyInlineMarkup {
-	if videoID == "telegram" {
+	if videoID == "telegram" || videoID == "live" {
 		return tg.NewKeyboard().
 			AddRow(
 				tg.Button.Data("🎛️", fmt.Sprintf("controls|%s|%d", videoID, chatID)),
@@ -177,6 +177,16 @@ func (mb *MakeButtons) ControlsMarkup(videoID string, chatID int64) *tg.ReplyInl
 		Build()
 }
 
+// ResumeMarkup returns buttons to resume or discard a restored queue
+func (mb *MakeButtons) ResumeMarkup(chatID int64) *tg.ReplyInlineMarkup {
+	return tg.NewKeyboard().
+		AddRow(
+			tg.Button.Data("▶ Resume", fmt.Sprintf("resume|play|%d", chatID)),
+			tg.Button.Data("🗑 Discard", fmt.Sprintf("resume|drop|%d", chatID)),
+		).
+		Build()
+}
+
 // SongMarkup returns song download buttons
 func (mb *MakeButtons) SongMarkup(randKey, url, key string) *tg.ReplyInlineMarkup {
 	return tg.NewKeyboard().
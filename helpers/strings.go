@@ -91,21 +91,29 @@ func (t TEXTS) System() string {
 }
 
 func (t TEXTS) HelpAdmin() string {
-	return "**👑 Admin Commands**\n\n/pause, /resume, /skip, /loop"
+	return "**👑 Admin Commands**\n\n/pause, /resume, /skip, /stop, /mute, /unmute, /loop, /seek, /seekback, /bass, /speed, /quality, /autostart, /present, /stoppresent, /record, /shuffle, /move, /clearqueue, /dedupe"
 }
 
 func (t TEXTS) HelpUser() string {
-	return "**👥 User Commands**\n\n/play, /queue, /current"
+	return "**👥 User Commands**\n\n/play, /stream, /queue, /current, /remove, /callme, /song"
 }
 
 func (t TEXTS) HelpSudo() string {
-	return "**⭐ Sudo Commands**\n\n/stats, /gban, /restart"
+	return "**⭐ Sudo Commands**\n\n/stats, /activevc, /assistants, /vclog, /gban, /autoend, /restart"
 }
 
 func (t TEXTS) HelpOwners() string {
 	return "**🔱 Owner Commands**\n\n/eval, /exec, /addsudo"
 }
 
+func (t TEXTS) HelpOthers() string {
+	return "**📎 Other Commands**\n\n/start, /help, /ping, /sysinfo"
+}
+
+func (t TEXTS) Source() string {
+	return "**📦 Source Code**\n\nThis bot is open source, check out the repo below!"
+}
+
 // Profile returns user profile text template
 // Args: levelSymbol, mention, id, userType, level, songsPlayed, joinDate, poweredBy
 func (t TEXTS) Profile() string {
@@ -126,7 +134,7 @@ func (t TEXTS) Profile() string {
 }
 
 // Stats returns bot statistics text template
-// Args: users, chats, gbans, blocked, songs, active, core, cpu, disk, ram, uptime, mention
+// Args: users, chats, gbans, blocked, songs, active, listeners, core, cpu, disk, ram, uptime, mention
 func (t TEXTS) Stats() string {
 	return `╭─────────────────────╮
 │  **📊 Bot Statistics**
@@ -138,6 +146,7 @@ func (t TEXTS) Stats() string {
 **⛔ Blocked:** ` + "`%d`" + `
 **🎵 Songs Played:** ` + "`%d`" + `
 **🎙️ Active VCs:** ` + "`%d`" + `
+**🎧 Listeners:** ` + "`%d`" + `
 **🔢 CPU Cores:** ` + "`%d`" + `
 **⚡ CPU Usage:** ` + "`%s`" + `
 **💾 Disk Usage:** ` + "`%s`" + `
@@ -163,6 +172,22 @@ func (t TEXTS) Playing() string {
 **👤 Requested By:** %s`
 }
 
+// ResumeOffer asks a chat to resume playback after a restart
+// Args: title, position, queued
+func (t TEXTS) ResumeOffer() string {
+	return `╭─────────────────────╮
+│  **♻️ Playback Interrupted**
+╰─────────────────────╯
+
+The bot restarted while this chat was playing.
+
+**📝 Song:** ` + "`%s`" + `
+**⏱️ Stopped At:** ` + "`%s`" + `
+**📋 In Queue:** ` + "`%d`" + `
+
+Resume from where it stopped?`
+}
+
 func (t TEXTS) QueueEmpty() string {
 	return `╭─────────────────────╮
 │  **📋 Queue**
@@ -27,18 +27,19 @@ type ProfileContext struct {
 
 // StatsContext contains bot statistics data
 type StatsContext struct {
-	Users   int
-	Chats   int
-	Gbans   int
-	Blocked int
-	Songs   int
-	Active  int
-	Core    int
-	CPU     string
-	Disk    string
-	RAM     string
-	Uptime  string
-	Mention string
+	Users     int
+	Chats     int
+	Gbans     int
+	Blocked   int
+	Songs     int
+	Active    int
+	Listeners int
+	Core      int
+	CPU       string
+	Disk      string
+	RAM       string
+	Uptime    string
+	Mention   string
 }
 
 // GetProfileText generates formatted profile text
@@ -102,6 +103,7 @@ func (um *UserModel) GetStatsText(ctx StatsContext) string {
 		ctx.Blocked,
 		ctx.Songs,
 		ctx.Active,
+		ctx.Listeners,
 		ctx.Core,
 		ctx.CPU,
 		ctx.Disk,
@@ -18,7 +18,7 @@ var (
 	isShuttingDown bool
 	globalClient   *core.Client
 	globalDB       *core.Database
-	globalCalls    *core.Calls
+	globalServices *handlers.Services
 )
 
 func main() {
@@ -92,24 +92,18 @@ func startBot(ctx context.Context, cfg *config.Config) error {
 	}
 	globalDB = db
 
+	// One calls manager and player for every plugin
+	log.Println(">> Booting NTgCalls...")
+	services := handlers.NewServices(client, db)
+	if err := services.Calls.Start(); err != nil {
+		return err
+	}
+	globalServices = services
+	log.Println("✅ NTgCalls initialized successfully!")
+
 	// Load all plugins
 	log.Println(">> Loading handler plugins...")
-	handlers.LoadAllPlugins(client, db)
-
-	// Initialize NTgCalls only if user client is available
-	if client.UserClient != nil {
-		log.Println(">> Booting NTgCalls...")
-		calls := core.NewCalls(client.UserClient)
-		if err := calls.Start(); err != nil {
-			log.Printf("⚠️  Failed to start NTgCalls: %v", err)
-			log.Println("   Voice chat features will not be available")
-		} else {
-			globalCalls = calls
-			log.Println("✅ NTgCalls initialized successfully!")
-		}
-	} else {
-		log.Println("⚠️  User client not available - NTgCalls disabled")
-	}
+	handlers.LoadAllPlugins(services)
 
 	// Send boot message
 	bootMsg := formatBootMessage()
@@ -126,7 +120,7 @@ func startBot(ctx context.Context, cfg *config.Config) error {
 		log.Println("⚠️  User Client:  NOT AVAILABLE")
 	}
 	log.Println("✅ Database:     CONNECTED")
-	if globalCalls != nil {
+	if globalServices != nil {
 		log.Println("✅ NTgCalls:     READY")
 	} else {
 		log.Println("⚠️  NTgCalls:     DISABLED")
@@ -155,24 +149,7 @@ func shutdownHandler(ctx context.Context, cancel context.CancelFunc) {
 	log.Println("🛑 Shutdown signal received. Stopping ShizuMusic...")
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 
-	if globalCalls != nil {
-		log.Println(">> Stopping NTgCalls...")
-		globalCalls.Stop()
-		log.Println("✅ NTgCalls stopped")
-	}
-
-	if globalClient != nil {
-		log.Println(">> Disconnecting Telegram clients...")
-		globalClient.Stop()
-		log.Println("✅ Telegram clients disconnected")
-	}
-
-	if globalDB != nil {
-		log.Println(">> Closing database connection...")
-		globalDB.Close()
-		log.Println("✅ Database connection closed")
-	}
-
+	// Sent while the bot is still connected
 	if globalClient != nil && globalClient.BotClient != nil {
 		offlineMsg := `#STOP
 
@@ -186,6 +163,22 @@ func shutdownHandler(ctx context.Context, cancel context.CancelFunc) {
 		}
 	}
 
+	if globalServices != nil {
+		log.Println(">> Stopping playback and disconnecting Telegram clients...")
+		globalServices.Stop()
+		log.Println("✅ NTgCalls stopped, Telegram clients disconnected")
+	} else if globalClient != nil {
+		log.Println(">> Disconnecting Telegram clients...")
+		globalClient.Stop()
+		log.Println("✅ Telegram clients disconnected")
+	}
+
+	if globalDB != nil {
+		log.Println(">> Closing database connection...")
+		globalDB.Close()
+		log.Println("✅ Database connection closed")
+	}
+
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 	log.Printf("👋 ShizuMusic [%s] is now offline!", version.Info.ShizuMusic)
 	log.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
@@ -1,6 +1,12 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// progressBarWidth is the number of segments in a progress bar
+const progressBarWidth = 12
 
 // SecsToMins converts seconds to formatted time string
 // Returns format: DD:HH:MM:SS, HH:MM:SS, MM:SS, or 00:SS
@@ -27,6 +33,39 @@ func SecsToMins(seconds int) string {
 	return "-"
 }
 
+// ProgressBar renders a track position like `1:23 ━━━●──── 3:45`
+// Unknown durations only show the position
+func ProgressBar(played, duration int) string {
+	if played < 0 {
+		played = 0
+	}
+	if duration <= 0 {
+		return ClockTime(played)
+	}
+	if played > duration {
+		played = duration
+	}
+
+	filled := played * (progressBarWidth - 1) / duration
+	bar := strings.Repeat("━", filled) + "●" + strings.Repeat("─", progressBarWidth-1-filled)
+	return fmt.Sprintf("%s %s %s", ClockTime(played), bar, ClockTime(duration))
+}
+
+// ClockTime formats seconds like a player clock: M:SS or H:MM:SS
+func ClockTime(seconds int) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	h := seconds / 3600
+	m := (seconds % 3600) / 60
+	s := seconds % 60
+
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
 // MinsToSecs converts formatted time string to seconds
 // Supports formats: DD:HH:MM:SS, HH:MM:SS, MM:SS
 func MinsToSecs(timeStr string) int {
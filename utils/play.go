@@ -3,7 +3,12 @@ package utils
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
+	"strings"
+	"sync"
+
+	"shizumusic/helpers"
 )
 
 // PlayContext contains information needed to play a track
@@ -22,9 +27,11 @@ type PlayContext struct {
 // VoiceChatManager interface for voice chat operations
 type VoiceChatManager interface {
 	JoinVC(ctx context.Context, chatID int64, file string, video bool) error
+	JoinVCAt(ctx context.Context, chatID int64, file string, video bool, offset int) error
 	LeaveVC(ctx context.Context, chatID int64, force bool) error
-	ChangeVC(ctx context.Context, chatID int64) error
-	ReplayVC(ctx context.Context, chatID int64, file string, video bool) error
+	ChangeStream(ctx context.Context, chatID int64, file string, video bool) error
+	SeekStream(ctx context.Context, chatID int64, file string, video bool, offset int) error
+	Position(ctx context.Context, chatID int64) int
 }
 
 // YouTubeDownloader interface - uses VideoInfo (matches YouTubeHandler.GetData)
@@ -32,6 +39,12 @@ type YouTubeDownloader interface {
 	Download(ctx context.Context, link string, isVideoID, isVideo bool) (string, error)
 	GetData(ctx context.Context, query string, single bool, limit int) ([]VideoInfo, error)
 	GetVideoInfo(ctx context.Context, videoID string) (*VideoInfo, error)
+	GetStreamURL(ctx context.Context, link string, isVideo bool) (string, error)
+}
+
+// PlaylistResolver lists the entries of a playlist link without downloading them
+type PlaylistResolver interface {
+	GetPlaylist(ctx context.Context, link string, limit int) ([]VideoInfo, error)
 }
 
 // ThumbnailGenerator interface for generating thumbnails
@@ -43,7 +56,8 @@ type ThumbnailGenerator interface {
 type PlayDatabase interface {
 	UpdateSongsCount(count int) error
 	UpdateUser(userID int64, key string, value interface{}) error
-	IsActiveVC(chatID int64) (bool, error)
+	GetLoop(chatID int64) (int, error)
+	SetLoop(chatID int64, count int) error
 }
 
 // PlayClient interface for client operations
@@ -63,6 +77,15 @@ type Player struct {
 	db        PlayDatabase
 	client    PlayClient
 	queue     *QueueDB
+	playlists PlaylistResolver
+
+	// Serializes queue progression per chat
+	locks   map[int64]*sync.Mutex
+	locksMu sync.Mutex
+
+	// Chats holding a restored queue that nobody resumed yet
+	pending   map[int64]bool
+	pendingMu sync.Mutex
 }
 
 // NewPlayer creates a new Player instance
@@ -74,14 +97,27 @@ func NewPlayer(
 	client PlayClient,
 	queue *QueueDB,
 ) *Player {
-	return &Player{
+	p := &Player{
 		vcManager: vcManager,
 		ytube:     ytube,
 		thumb:     thumb,
 		db:        db,
 		client:    client,
 		queue:     queue,
+		locks:     make(map[int64]*sync.Mutex),
+		pending:   make(map[int64]bool),
+	}
+
+	// Downloaders that can list playlists double as the default resolver
+	if resolver, ok := ytube.(PlaylistResolver); ok {
+		p.playlists = resolver
 	}
+	return p
+}
+
+// SetPlaylistResolver replaces the resolver used by ImportPlaylist
+func (p *Player) SetPlaylistResolver(resolver PlaylistResolver) {
+	p.playlists = resolver
 }
 
 // MessageEditable interface for messages that can be edited/deleted
@@ -102,7 +138,7 @@ func (p *Player) Play(ctx context.Context, message MessageEditable, playCtx Play
 	var filePath string
 	var err error
 
-	if playCtx.VideoID == "telegram" {
+	if playCtx.VideoID == "telegram" || playCtx.VideoID == "live" {
 		filePath = playCtx.File
 	} else {
 		if edit {
@@ -124,7 +160,10 @@ func (p *Player) Play(ctx context.Context, message MessageEditable, playCtx Play
 		}
 	}
 
-	// Add to queue
+	// Queue under the chat lock, so the track can't land between changeVC
+	// finding the queue empty and leaveVC clearing it
+	lock := p.chatLock(playCtx.ChatID)
+	lock.Lock()
 	position := p.queue.PutQueue(
 		playCtx.ChatID,
 		playCtx.UserID,
@@ -136,10 +175,18 @@ func (p *Player) Play(ctx context.Context, message MessageEditable, playCtx Play
 		playCtx.VCType,
 		playCtx.Force,
 	)
+	lock.Unlock()
 
 	if position == 0 {
 		return p.playNow(ctx, message, playCtx, filePath)
 	}
+
+	// A queue restored after a restart is waiting for someone to resume it
+	if p.IsPending(playCtx.ChatID) {
+		if err := p.ResumeQueue(ctx, playCtx.ChatID); err != nil {
+			return err
+		}
+	}
 	return p.addToQueue(ctx, message, playCtx, position)
 }
 
@@ -148,7 +195,15 @@ func (p *Player) playNow(ctx context.Context, message MessageEditable, playCtx P
 	photo := p.thumb.Generate(359, 297, playCtx.VideoID)
 	video := playCtx.VCType == "video"
 
-	if err := p.vcManager.JoinVC(ctx, playCtx.ChatID, filePath, video); err != nil {
+	source := filePath
+	var err error
+	if playCtx.VideoID == "live" {
+		source, err = p.liveSource(ctx, filePath, video)
+	}
+	if err == nil {
+		err = p.vcManager.JoinVC(ctx, playCtx.ChatID, source, video)
+	}
+	if err != nil {
 		message.Delete(ctx)
 		if p.client != nil {
 			p.client.SendMessage(ctx, playCtx.ChatID, fmt.Sprintf("❌ Failed to join VC: %v", err), nil)
@@ -163,21 +218,8 @@ func (p *Player) playNow(ctx context.Context, message MessageEditable, playCtx P
 		return err
 	}
 
-	text := fmt.Sprintf(
-		"╭─────────────────────╮\n"+
-			"│  **🎵 Now Playing**\n"+
-			"╰─────────────────────╯\n\n"+
-			"**📝 Song:** `%s`\n"+
-			"**⏱️ Duration:** `%s`\n"+
-			"**👤 Requested By:** %s",
-		playCtx.Title,
-		playCtx.Duration,
-		playCtx.User,
-	)
-
-	if p.client != nil {
-		p.client.SendMessage(ctx, playCtx.ChatID, text, nil)
-	}
+	text := nowPlayingText(playCtx.Title, playCtx.Duration, playCtx.User)
+	p.sendNowPlaying(ctx, playCtx.ChatID, playCtx.VideoID, text)
 	message.Delete(ctx)
 
 	// Update stats
@@ -219,134 +261,503 @@ func (p *Player) addToQueue(ctx context.Context, message MessageEditable, playCt
 // Skip skips the current track
 func (p *Player) Skip(ctx context.Context, chatID int64, message MessageEditable) error {
 	message.Edit(ctx, "⏭️ Skipping ...")
-	if err := p.vcManager.ChangeVC(ctx, chatID); err != nil {
+
+	// The skipped track isn't replayed, the loop carries on with the next one
+	if err := p.changeVC(ctx, chatID, false); err != nil {
 		return err
 	}
 	message.Delete(ctx)
 	return nil
 }
 
-// Replay replays the current track
-func (p *Player) Replay(ctx context.Context, chatID int64, message MessageEditable) error {
-	que := p.queue.GetCurrent(chatID)
-	if que == nil {
-		return message.Edit(ctx, "❌ Nothing is playing to replay")
+// ChangeVC drops the current track and streams the next one in queue
+// While a loop is set the current track is replayed instead
+// Leaves the voice chat once the queue runs out
+func (p *Player) ChangeVC(ctx context.Context, chatID int64) error {
+	return p.changeVC(ctx, chatID, true)
+}
+
+// changeVC is ChangeVC, replay tells whether a set loop replays the current track
+// The pop, the next track lookup and leaveVC's queue clear share one chat lock
+// hold, enqueueing takes the same lock so no new track is dropped in between
+func (p *Player) changeVC(ctx context.Context, chatID int64, replay bool) error {
+	lock := p.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	loop := 0
+	if p.db != nil && replay {
+		loop, _ = p.db.GetLoop(chatID)
 	}
 
-	video := que.VCType == "video"
-	photo := p.thumb.Generate(359, 297, que.VideoID)
+	// Live streams have no end to loop back from
+	if current := p.queue.GetCurrent(chatID); loop > 0 && current != nil && !current.IsLive() {
+		p.db.SetLoop(chatID, loop-1)
+		p.queue.SetPlayed(chatID, 0)
+	} else {
+		p.popCurrent(chatID)
+	}
 
-	var filePath string
-	var err error
+	for {
+		que := p.queue.GetCurrent(chatID)
+		if que == nil {
+			return p.leaveVC(ctx, chatID)
+		}
 
-	if que.File == que.VideoID {
-		filePath, err = p.ytube.Download(ctx, que.VideoID, true, video)
+		video := que.VCType == "video"
+		filePath, err := p.resolveFile(ctx, que)
 		if err != nil {
+			log.Printf(">> Download failed for %s in chat %d: %v", que.VideoID, chatID, err)
+			p.notify(ctx, chatID, fmt.Sprintf("❌ Failed to download `%s`, skipping ...", que.Title))
+			p.popCurrent(chatID)
+			continue
+		}
+
+		if err := p.vcManager.ChangeStream(ctx, chatID, filePath, video); err != nil {
+			p.notify(ctx, chatID, fmt.Sprintf("❌ Failed to change stream: %v", err))
+			p.leaveVC(ctx, chatID)
 			return err
 		}
+
+		p.sendNowPlaying(ctx, chatID, que.VideoID, nowPlayingText(que.Title, que.Duration, que.User))
+
+		if p.db != nil {
+			p.db.UpdateSongsCount(1)
+			p.db.UpdateUser(que.UserID, "songs_played", 1)
+		}
+		return nil
+	}
+}
+
+// Stop leaves the voice chat and clears the queue of a chat
+func (p *Player) Stop(ctx context.Context, chatID int64) error {
+	lock := p.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return p.leaveVC(ctx, chatID)
+}
+
+// Seek moves playback of the current track by the given seconds
+// Negative values seek backward, never past the start of the track
+// Returns the position it seeked to, counted from the live stream position
+func (p *Player) Seek(ctx context.Context, chatID int64, seconds int) (int, error) {
+	lock := p.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	que := p.queue.GetCurrent(chatID)
+	if que == nil {
+		return 0, NewUserException("Nothing is playing right now!")
+	}
+	if que.IsLive() {
+		return 0, NewUserException("Seeking is not possible in live streams!")
+	}
+
+	duration := MinsToSecs(que.Duration)
+	if duration <= 0 {
+		return 0, NewUserException("Cannot seek in a track with unknown duration!")
+	}
+
+	target := p.vcManager.Position(ctx, chatID) + seconds
+	if target < 0 {
+		target = 0
+	}
+	if target >= duration {
+		return 0, NewUserException(fmt.Sprintf(
+			"Cannot seek to `%s`, the track is only `%s` long!",
+			SecsToMins(target), que.Duration,
+		))
+	}
+
+	filePath, err := p.resolveFile(ctx, que)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.vcManager.SeekStream(ctx, chatID, filePath, que.VCType == "video", target); err != nil {
+		return 0, err
+	}
+
+	p.queue.SetPlayed(chatID, target)
+	return target, nil
+}
+
+// Restart rebuilds the stream of the current track at its current position
+// Used to apply changed stream settings without leaving the voice chat
+func (p *Player) Restart(ctx context.Context, chatID int64) error {
+	lock := p.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	que := p.queue.GetCurrent(chatID)
+	if que == nil {
+		return NewUserException("Nothing is playing right now!")
+	}
+
+	// Live streams rejoin at the live edge
+	position := 0
+	if !que.IsLive() {
+		position = p.vcManager.Position(ctx, chatID)
+	}
+	filePath, err := p.resolveFile(ctx, que)
+	if err != nil {
+		return err
+	}
+
+	if err := p.vcManager.SeekStream(ctx, chatID, filePath, que.VCType == "video", position); err != nil {
+		return err
+	}
+
+	p.queue.SetPlayed(chatID, position)
+	return nil
+}
+
+// SyncPosition stores the stream position of the playing track as its Played time
+// Chats busy changing tracks are skipped, the next sync catches up
+func (p *Player) SyncPosition(ctx context.Context, chatID int64) {
+	lock := p.chatLock(chatID)
+	if !lock.TryLock() {
+		return
+	}
+	defer lock.Unlock()
+
+	if p.IsPending(chatID) {
+		return
+	}
+	que := p.queue.GetCurrent(chatID)
+	if que == nil || que.IsLive() {
+		return
+	}
+	p.queue.SetPlayed(chatID, p.vcManager.Position(ctx, chatID))
+}
+
+// RestoreQueue loads a saved queue without joining the voice chat
+// It stays pending until ResumeQueue or Discard is called
+func (p *Player) RestoreQueue(chatID int64, items []QueueItem) {
+	lock := p.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	p.queue.RestoreQueue(chatID, items)
+	p.setPending(chatID, true)
+}
+
+// IsPending reports whether a chat holds a restored queue waiting to be resumed
+func (p *Player) IsPending(chatID int64) bool {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+
+	return p.pending[chatID]
+}
+
+func (p *Player) setPending(chatID int64, pending bool) {
+	p.pendingMu.Lock()
+	defer p.pendingMu.Unlock()
+
+	if pending {
+		p.pending[chatID] = true
 	} else {
-		filePath = que.File
+		delete(p.pending, chatID)
 	}
+}
 
-	if err := p.vcManager.ReplayVC(ctx, chatID, filePath, video); err != nil {
-		message.Delete(ctx)
-		if p.client != nil {
-			p.client.SendMessage(ctx, chatID, fmt.Sprintf("❌ Replay failed: %v", err), nil)
-		}
-		p.queue.ClearQueue(chatID)
-		if filePath != que.File {
-			os.Remove(filePath)
-		}
-		if photo != "" {
-			os.Remove(photo)
-		}
+// ResumeQueue rejoins the voice chat of a restored queue
+// The current track continues from its saved Played position
+func (p *Player) ResumeQueue(ctx context.Context, chatID int64) error {
+	lock := p.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	que := p.queue.GetCurrent(chatID)
+	if !p.IsPending(chatID) || que == nil {
+		return NewUserException("There is no saved queue to resume!")
+	}
+	p.setPending(chatID, false)
+
+	filePath, err := p.resolveFile(ctx, que)
+	if err != nil {
+		p.dropQueue(chatID)
 		return err
 	}
 
-	text := fmt.Sprintf(
-		"╭─────────────────────╮\n"+
-			"│  **🎵 Now Playing**\n"+
-			"╰─────────────────────╯\n\n"+
-			"**📝 Song:** `%s`\n"+
-			"**⏱️ Duration:** `%s`\n"+
-			"**👤 Requested By:** %s",
-		que.Title,
-		que.Duration,
-		que.User,
-	)
+	if err := p.vcManager.JoinVCAt(ctx, chatID, filePath, que.VCType == "video", que.Played); err != nil {
+		p.dropQueue(chatID)
+		return err
+	}
+
+	p.sendNowPlaying(ctx, chatID, que.VideoID, nowPlayingText(que.Title, que.Duration, que.User))
+	return nil
+}
+
+// Discard drops a restored queue without joining the voice chat
+func (p *Player) Discard(ctx context.Context, chatID int64) error {
+	lock := p.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !p.IsPending(chatID) {
+		return NewUserException("There is no saved queue to discard!")
+	}
+	p.dropQueue(chatID)
+	return nil
+}
+
+// resolveFile returns a playable path for a queued track
+// Lazily queued tracks only hold their video ID and get downloaded here
+func (p *Player) resolveFile(ctx context.Context, que *QueueItem) (string, error) {
+	if que.VideoID == "telegram" {
+		return que.File, nil
+	}
+	if que.IsLive() {
+		return p.liveSource(ctx, que.File, que.VCType == "video")
+	}
+	if _, err := os.Stat(que.File); err == nil {
+		return que.File, nil
+	}
+
+	filePath, err := p.ytube.Download(ctx, que.VideoID, true, que.VCType == "video")
+	if err != nil {
+		return "", err
+	}
+	p.queue.SetCurrentFile(que.ChatID, filePath)
+	return filePath, nil
+}
+
+// liveSource returns the URL ffmpeg should read for a live stream link
+// YouTube live links are resolved to their current stream URL
+func (p *Player) liveSource(ctx context.Context, link string, video bool) (string, error) {
+	if !strings.Contains(link, "youtube.com") && !strings.Contains(link, "youtu.be") {
+		return link, nil
+	}
+	return p.ytube.GetStreamURL(ctx, link, video)
+}
+
+// popCurrent removes the current track and its file if nothing else needs it
+func (p *Player) popCurrent(chatID int64) {
+	prev := p.queue.PopCurrent(chatID)
+	if prev == nil {
+		return
+	}
+	p.queue.RemoveCache(chatID, prev.File)
+	p.cleanup(prev.File)
+}
+
+// leaveVC leaves the voice chat and drops everything queued for it
+func (p *Player) leaveVC(ctx context.Context, chatID int64) error {
+	p.dropQueue(chatID)
+	return p.vcManager.LeaveVC(ctx, chatID, false)
+}
+
+// dropQueue clears the queue, files and player state of a chat
+func (p *Player) dropQueue(chatID int64) {
+	p.setPending(chatID, false)
+
+	cache := p.queue.GetCache(chatID)
+	p.queue.ClearQueue(chatID)
+	for _, file := range cache {
+		p.cleanup(file)
+	}
+
+	if p.db != nil {
+		p.db.SetLoop(chatID, 0)
+	}
+}
+
+// CleanupTracks removes the files of tracks taken out of a queue
+func (p *Player) CleanupTracks(items []QueueItem) {
+	for _, item := range items {
+		p.cleanup(item.File)
+	}
+}
+
+// cleanup removes a downloaded file once no queue references it anymore
+func (p *Player) cleanup(file string) {
+	if file == "" || p.queue.IsCached(file) {
+		return
+	}
+	if _, err := os.Stat(file); err != nil {
+		return
+	}
+	os.Remove(file)
+}
 
+// notify sends a plain message to the chat if a client is available
+func (p *Player) notify(ctx context.Context, chatID int64, text string) {
 	if p.client != nil {
 		p.client.SendMessage(ctx, chatID, text, nil)
 	}
-	message.Delete(ctx)
+}
 
-	if photo != "" {
-		os.Remove(photo)
+// sendNowPlaying posts a now playing message with the player buttons
+func (p *Player) sendNowPlaying(ctx context.Context, chatID int64, videoID, text string) {
+	if p.client == nil {
+		return
+	}
+	btns := helpers.Buttons.PlayerMarkup(chatID, videoID, p.client.GetBotUsername())
+	p.client.SendMessage(ctx, chatID, text, btns)
+}
+
+// chatLock returns the progression lock of a chat
+func (p *Player) chatLock(chatID int64) *sync.Mutex {
+	p.locksMu.Lock()
+	defer p.locksMu.Unlock()
+
+	lock, ok := p.locks[chatID]
+	if !ok {
+		lock = &sync.Mutex{}
+		p.locks[chatID] = lock
+	}
+	return lock
+}
+
+// Replay restarts the current track from its beginning
+// The call is kept, only its stream sources are rebuilt at the start
+func (p *Player) Replay(ctx context.Context, chatID int64, message MessageEditable) error {
+	lock := p.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	que := p.queue.GetCurrent(chatID)
+	if que == nil {
+		return message.Edit(ctx, "❌ Nothing is playing to replay")
+	}
+
+	filePath, err := p.resolveFile(ctx, que)
+	if err == nil {
+		err = p.vcManager.SeekStream(ctx, chatID, filePath, que.VCType == "video", 0)
 	}
+	if err != nil {
+		message.Edit(ctx, fmt.Sprintf("❌ Replay failed: %v", err))
+		return err
+	}
+	p.queue.SetPlayed(chatID, 0)
+
+	text := nowPlayingText(que.Title, que.Duration, que.User)
+	p.sendNowPlaying(ctx, chatID, que.VideoID, text)
+	message.Delete(ctx)
 	return nil
 }
 
 // Playlist plays multiple tracks from a playlist
+// Each entry of collection is looked up on YouTube before queueing
 func (p *Player) Playlist(ctx context.Context, message MessageEditable, chatID, userID int64, userMention string, collection []string, video bool) error {
+	message.Edit(ctx, "📋 Adding songs from playlist to queue...\n\n__This might take some time!__")
+
+	var tracks []VideoInfo
+	for _, item := range collection {
+		dataList, err := p.ytube.GetData(ctx, item, true, 1)
+		if err != nil || len(dataList) == 0 {
+			continue
+		}
+		tracks = append(tracks, dataList[0])
+	}
+
+	return p.EnqueueTracks(ctx, message, chatID, userID, userMention, tracks, video, len(collection)-len(tracks))
+}
+
+// ImportPlaylist queues the entries of a playlist link, up to PlaylistLimit
+func (p *Player) ImportPlaylist(ctx context.Context, message MessageEditable, chatID, userID int64, userMention, link string, video bool) error {
+	if p.playlists == nil {
+		return NewUserException("Playlists are not supported!")
+	}
+
+	message.Edit(ctx, "📋 Fetching playlist ...")
+
+	tracks, err := p.playlists.GetPlaylist(ctx, link, PlaylistLimit())
+	if err != nil {
+		log.Printf(">> Playlist lookup failed for %s: %v", link, err)
+		return NewUserException("Could not fetch the playlist, make sure it is public!")
+	}
+
+	return p.EnqueueTracks(ctx, message, chatID, userID, userMention, tracks, video, 0)
+}
+
+// EnqueueTracks queues tracks in bulk and starts playback if the chat was idle
+// Tracks are queued by video ID only and get downloaded once they come up
+func (p *Player) EnqueueTracks(ctx context.Context, message MessageEditable, chatID, userID int64, userMention string, tracks []VideoInfo, video bool, failed int) error {
+	if len(tracks) == 0 {
+		return NewUserException("No playable tracks found!")
+	}
+
 	vcType := "voice"
 	if video {
 		vcType = "video"
 	}
 
-	count := 0
-	failed := 0
+	// Held like in Play, a queue emptying meanwhile would wipe the new tracks
+	lock := p.chatLock(chatID)
+	lock.Lock()
+	idle := p.queue.GetQueueLength(chatID) == 0
+	// Queueing is instant, the summary below is the only update
+	for _, track := range tracks {
+		p.queue.PutQueue(chatID, userID, track.Duration, track.ID, track.Title, userMention, track.ID, vcType, false)
+	}
+	lock.Unlock()
 
-	isActive, _ := p.db.IsActiveVC(chatID)
-	if isActive {
-		message.Edit(ctx, "📋 Adding songs from playlist to queue...\n\n__This might take some time!__")
+	switch {
+	case idle:
+		message.Edit(ctx, "⬇️ Downloading ...")
+		if err := p.startQueue(ctx, chatID); err != nil {
+			message.Edit(ctx, fmt.Sprintf("❌ Failed to join VC: %v", err))
+			return err
+		}
+	case p.IsPending(chatID):
+		if err := p.ResumeQueue(ctx, chatID); err != nil {
+			return err
+		}
 	}
 
-	previously := p.queue.GetQueueLength(chatID)
+	message.Edit(ctx, fmt.Sprintf("✅ **Added all tracks to queue!**\n\n**Total:** `%d`\n**Failed:** `%d`", len(tracks), failed))
+	return nil
+}
 
-	for _, item := range collection {
-		dataList, err := p.ytube.GetData(ctx, item, true, 1)
-		if err != nil || len(dataList) == 0 {
-			failed++
+// startQueue joins the voice chat with the first queued track
+// Queued tracks that fail to download are skipped
+func (p *Player) startQueue(ctx context.Context, chatID int64) error {
+	lock := p.chatLock(chatID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	for {
+		que := p.queue.GetCurrent(chatID)
+		if que == nil {
+			return NewUserException("None of the tracks could be downloaded!")
+		}
+
+		filePath, err := p.resolveFile(ctx, que)
+		if err != nil {
+			log.Printf(">> Download failed for %s in chat %d: %v", que.VideoID, chatID, err)
+			p.popCurrent(chatID)
 			continue
 		}
 
-		data := dataList[0]
+		if err := p.vcManager.JoinVC(ctx, chatID, filePath, que.VCType == "video"); err != nil {
+			p.dropQueue(chatID)
+			return err
+		}
 
-		if count == 0 && previously == 0 {
-			filePath, err := p.ytube.Download(ctx, data.ID, true, video)
-			if err != nil {
-				failed++
-				continue
-			}
+		p.sendNowPlaying(ctx, chatID, que.VideoID, nowPlayingText(que.Title, que.Duration, que.User))
 
-			p.queue.PutQueue(chatID, userID, data.Duration, filePath, data.Title, userMention, data.ID, vcType, false)
-
-			photo := p.thumb.Generate(359, 297, data.ID)
-			if err := p.vcManager.JoinVC(ctx, chatID, filePath, video); err != nil {
-				message.Edit(ctx, fmt.Sprintf("❌ Failed to join VC: %v", err))
-				p.queue.ClearQueue(chatID)
-				os.Remove(filePath)
-				if photo != "" {
-					os.Remove(photo)
-				}
-				return err
-			}
-
-			text := fmt.Sprintf("**🎵 Now Playing**\n\n**📝 Song:** `%s`\n**⏱️ Duration:** `%s`\n**👤 By:** %s",
-				data.Title, data.Duration, userMention)
-			if p.client != nil {
-				p.client.SendMessage(ctx, chatID, text, nil)
-			}
-			if photo != "" {
-				os.Remove(photo)
-			}
-		} else {
-			p.queue.PutQueue(chatID, userID, data.Duration, data.ID, data.Title, userMention, data.ID, vcType, false)
+		if p.db != nil {
+			p.db.UpdateSongsCount(1)
+			p.db.UpdateUser(que.UserID, "songs_played", 1)
 		}
-
-		count++
+		return nil
 	}
+}
 
-	message.Edit(ctx, fmt.Sprintf("✅ **Added all tracks to queue!**\n\n**Total:** `%d`\n**Failed:** `%d`", count, failed))
-	return nil
+// nowPlayingText formats the now playing message of a track
+func nowPlayingText(title, duration, user string) string {
+	return fmt.Sprintf(
+		"╭─────────────────────╮\n"+
+			"│  **🎵 Now Playing**\n"+
+			"╰─────────────────────╯\n\n"+
+			"**📝 Song:** `%s`\n"+
+			"**⏱️ Duration:** `%s`\n"+
+			"**👤 Requested By:** %s",
+		title,
+		duration,
+		user,
+	)
 }
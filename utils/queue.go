@@ -1,6 +1,9 @@
 package utils
 
-import "sync"
+import (
+	"math/rand"
+	"sync"
+)
 
 // QueueItem represents a track in the queue
 type QueueItem struct {
@@ -15,10 +18,27 @@ type QueueItem struct {
 	Played   int    `json:"played"`  // Seconds already played
 }
 
+// IsLive reports whether the track is a live stream without a fixed duration
+func (q *QueueItem) IsLive() bool {
+	return q.VideoID == "live"
+}
+
+// dedupeKey identifies the track behind a queue item
+// Only YouTube tracks have their own video ID, Telegram files and live streams
+// share a placeholder ID and are told apart by their file or URL
+func (q *QueueItem) dedupeKey() string {
+	switch q.VideoID {
+	case "", "telegram", "live":
+		return q.VideoID + "|" + q.File
+	}
+	return q.VideoID
+}
+
 // QueueDB manages music queues for all chats
 type QueueDB struct {
 	queue map[int64][]QueueItem
 	cache map[int64][]string // Cache for file paths
+	dirty map[int64]bool     // Chats changed since the last DirtyChats call
 	mu    sync.RWMutex
 }
 
@@ -27,6 +47,7 @@ func NewQueueDB() *QueueDB {
 	return &QueueDB{
 		queue: make(map[int64][]QueueItem),
 		cache: make(map[int64][]string),
+		dirty: make(map[int64]bool),
 	}
 }
 
@@ -76,6 +97,7 @@ func (q *QueueDB) PutQueue(
 		q.cache[chatID] = []string{}
 	}
 	q.cache[chatID] = append(q.cache[chatID], file)
+	q.dirty[chatID] = true
 
 	// Return position (0-indexed)
 	position := len(q.queue[chatID]) - 1
@@ -128,6 +150,7 @@ func (q *QueueDB) RmQueue(chatID int64, index int) string {
 
 	// Remove from queue
 	q.queue[chatID] = append(queue[:index], queue[index+1:]...)
+	q.dirty[chatID] = true
 
 	return file
 }
@@ -139,6 +162,7 @@ func (q *QueueDB) ClearQueue(chatID int64) {
 
 	q.queue[chatID] = []QueueItem{}
 	q.cache[chatID] = []string{}
+	q.dirty[chatID] = true
 }
 
 // GetCurrent returns the currently playing track (first in queue)
@@ -168,6 +192,7 @@ func (q *QueueDB) PopCurrent(chatID int64) *QueueItem {
 
 	current := queue[0]
 	q.queue[chatID] = queue[1:]
+	q.dirty[chatID] = true
 
 	return &current
 }
@@ -193,6 +218,7 @@ func (q *QueueDB) UpdateDuration(chatID int64, seekType int, time int) {
 		// Forward
 		q.queue[chatID][0].Played += time
 	}
+	q.dirty[chatID] = true
 }
 
 // GetPlayed returns seconds already played for current track
@@ -208,7 +234,12 @@ func (q *QueueDB) GetPlayed(chatID int64) int {
 	return queue[0].Played
 }
 
+// playedJump is how far the played time may move in one update before it counts as a seek
+// Playback only adds about a second between two position syncs
+const playedJump = 5
+
 // SetPlayed sets the played duration for current track
+// Only seeks and replays mark the chat dirty, steady playback doesn't
 func (q *QueueDB) SetPlayed(chatID int64, played int) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
@@ -218,7 +249,11 @@ func (q *QueueDB) SetPlayed(chatID int64, played int) {
 		return
 	}
 
+	previous := queue[0].Played
 	q.queue[chatID][0].Played = played
+	if played < previous || played-previous > playedJump {
+		q.dirty[chatID] = true
+	}
 }
 
 // IsQueueEmpty checks if queue is empty
@@ -253,5 +288,221 @@ func (q *QueueDB) ClearCache(chatID int64) {
 	q.cache[chatID] = []string{}
 }
 
+// SetCurrentFile updates the file path of the current track
+// Used once a lazily queued track has been downloaded
+func (q *QueueDB) SetCurrentFile(chatID int64, file string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue := q.queue[chatID]
+	if queue == nil || len(queue) == 0 {
+		return
+	}
+
+	old := queue[0].File
+	q.queue[chatID][0].File = file
+	q.dirty[chatID] = true
+
+	for i, cached := range q.cache[chatID] {
+		if cached == old {
+			q.cache[chatID][i] = file
+			return
+		}
+	}
+	q.cache[chatID] = append(q.cache[chatID], file)
+}
+
+// RemoveCache drops one reference of a file from the chat cache
+func (q *QueueDB) RemoveCache(chatID int64, file string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	cache := q.cache[chatID]
+	for i, cached := range cache {
+		if cached == file {
+			q.cache[chatID] = append(cache[:i], cache[i+1:]...)
+			return
+		}
+	}
+}
+
+// IsCached checks if any chat still references a file
+func (q *QueueDB) IsCached(file string) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	for _, cache := range q.cache {
+		for _, cached := range cache {
+			if cached == file {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ShuffleQueue shuffles the upcoming tracks, the current track stays in place
+// Returns the number of shuffled tracks
+func (q *QueueDB) ShuffleQueue(chatID int64) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue := q.queue[chatID]
+	if len(queue) < 3 {
+		return 0
+	}
+
+	upcoming := queue[1:]
+	rand.Shuffle(len(upcoming), func(i, j int) {
+		upcoming[i], upcoming[j] = upcoming[j], upcoming[i]
+	})
+	q.dirty[chatID] = true
+	return len(upcoming)
+}
+
+// MoveQueue moves the upcoming track at index from to index to
+// Index 0 is the current track and can neither be moved nor replaced
+func (q *QueueDB) MoveQueue(chatID int64, from, to int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue := q.queue[chatID]
+	if from < 1 || to < 1 || from >= len(queue) || to >= len(queue) {
+		return false
+	}
+	if from == to {
+		return true
+	}
+
+	item := queue[from]
+	if from < to {
+		copy(queue[from:to], queue[from+1:to+1])
+	} else {
+		copy(queue[to+1:from+1], queue[to:from])
+	}
+	queue[to] = item
+	q.dirty[chatID] = true
+	return true
+}
+
+// RemoveQueue removes the upcoming tracks from position from to to, both included
+// With a non-zero owner nothing is removed unless that user requested every track in range
+// Returns the removed tracks, or the position of the first track of someone else
+func (q *QueueDB) RemoveQueue(chatID int64, from, to int, owner int64) ([]QueueItem, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue := q.queue[chatID]
+	if from < 1 || to < from || to >= len(queue) {
+		return nil, 0
+	}
+	if owner != 0 {
+		for i := from; i <= to; i++ {
+			if queue[i].UserID != owner {
+				return nil, i
+			}
+		}
+	}
+
+	removed := append([]QueueItem{}, queue[from:to+1]...)
+	kept := make([]QueueItem, 0, len(queue)-len(removed))
+	kept = append(kept, queue[:from]...)
+	kept = append(kept, queue[to+1:]...)
+
+	q.queue[chatID] = kept
+	q.forget(chatID, removed)
+	q.dirty[chatID] = true
+	return removed, 0
+}
+
+// ClearUpcoming removes every track after the current one
+// Returns the removed tracks
+func (q *QueueDB) ClearUpcoming(chatID int64) []QueueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue := q.queue[chatID]
+	if len(queue) < 2 {
+		return nil
+	}
+
+	removed := append([]QueueItem{}, queue[1:]...)
+	q.queue[chatID] = queue[:1]
+	q.forget(chatID, removed)
+	q.dirty[chatID] = true
+	return removed
+}
+
+// DedupeQueue removes upcoming tracks that are already queued earlier
+// Returns the removed tracks
+func (q *QueueDB) DedupeQueue(chatID int64) []QueueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queue := q.queue[chatID]
+	if len(queue) < 2 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(queue))
+	kept := make([]QueueItem, 0, len(queue))
+	var removed []QueueItem
+	for i, item := range queue {
+		key := item.dedupeKey()
+		if i > 0 && seen[key] {
+			removed = append(removed, item)
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, item)
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	q.queue[chatID] = kept
+	q.forget(chatID, removed)
+	q.dirty[chatID] = true
+	return removed
+}
+
+// forget drops one cache reference per removed track, caller holds the lock
+func (q *QueueDB) forget(chatID int64, removed []QueueItem) {
+	for _, item := range removed {
+		cache := q.cache[chatID]
+		for i, cached := range cache {
+			if cached == item.File {
+				q.cache[chatID] = append(cache[:i], cache[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// RestoreQueue replaces the queue of a chat with previously saved tracks
+func (q *QueueDB) RestoreQueue(chatID int64, items []QueueItem) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.queue[chatID] = append([]QueueItem{}, items...)
+	q.cache[chatID] = make([]string, 0, len(items))
+	for _, item := range items {
+		q.cache[chatID] = append(q.cache[chatID], item.File)
+	}
+}
+
+// DirtyChats returns and resets the chats whose queue changed since the last call
+func (q *QueueDB) DirtyChats() []int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	chats := make([]int64, 0, len(q.dirty))
+	for chatID := range q.dirty {
+		chats = append(chats, chatID)
+	}
+	q.dirty = make(map[int64]bool)
+	return chats
+}
+
 // Global queue instance
 var Queue = NewQueueDB()
@@ -35,7 +35,7 @@ func NewThumbnail() *Thumbnail {
 // Generate creates a thumbnail image for a video
 // Returns the file path of generated thumbnail
 func (t *Thumbnail) Generate(width, height int, videoID string) string {
-	if videoID == "" || videoID == "telegram" {
+	if videoID == "" || videoID == "telegram" || videoID == "live" {
 		return ""
 	}
 
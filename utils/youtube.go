@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -410,6 +411,27 @@ func (y *YouTubeHandler) GetVideoInfo(ctx context.Context, videoID string) (*Vid
 	}, nil
 }
 
+// GetStreamURL resolves a YouTube live link to a direct stream URL ffmpeg can read
+// The URLs expire after a few hours, resolve them right before streaming
+func (y *YouTubeHandler) GetStreamURL(ctx context.Context, link string, isVideo bool) (string, error) {
+	format := "bestaudio/best"
+	if isVideo {
+		format = "best[height<=720]/best"
+	}
+
+	cmd := exec.CommandContext(ctx, "yt-dlp", "-g", "-f", format, "--no-warnings", link)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get stream URL: %w", err)
+	}
+
+	streamURL := strings.TrimSpace(strings.SplitN(string(output), "\n", 2)[0])
+	if streamURL == "" {
+		return "", fmt.Errorf("no stream URL found")
+	}
+	return streamURL, nil
+}
+
 // FormatLink formats a link from video ID or URL
 func (y *YouTubeHandler) FormatLink(link string, isVideoID bool) string {
 	if isVideoID {
@@ -462,6 +484,86 @@ func ExtractPlaylistID(url string) string {
 	return ""
 }
 
+// defaultPlaylistLimit caps playlist imports when PLAYLIST_LIMIT is unset
+const defaultPlaylistLimit = 50
+
+// PlaylistLimit returns how many tracks a playlist import may queue
+// Set PLAYLIST_LIMIT in the environment to change it
+func PlaylistLimit() int {
+	if limit, err := strconv.Atoi(os.Getenv("PLAYLIST_LIMIT")); err == nil && limit > 0 {
+		return limit
+	}
+	return defaultPlaylistLimit
+}
+
+// GetPlaylist lists up to limit entries of a playlist without downloading them
+// Uses yt-dlp --flat-playlist, so only metadata is fetched
+func (y *YouTubeHandler) GetPlaylist(ctx context.Context, link string, limit int) ([]VideoInfo, error) {
+	playlistID := ExtractPlaylistID(link)
+	if playlistID == "" {
+		return nil, fmt.Errorf("invalid playlist URL")
+	}
+	if limit <= 0 {
+		limit = PlaylistLimit()
+	}
+
+	cmd := exec.CommandContext(ctx, "yt-dlp",
+		"--flat-playlist", "-J", "--no-warnings",
+		"--playlist-end", strconv.Itoa(limit),
+		fmt.Sprintf("https://www.youtube.com/playlist?list=%s", playlistID),
+	)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get playlist: %w", err)
+	}
+
+	var playlist struct {
+		Entries []struct {
+			ID       string   `json:"id"`
+			Title    string   `json:"title"`
+			Duration *float64 `json:"duration"`
+			Channel  string   `json:"channel"`
+			Uploader string   `json:"uploader"`
+		} `json:"entries"`
+	}
+
+	if err := json.Unmarshal(output, &playlist); err != nil {
+		return nil, err
+	}
+
+	var videos []VideoInfo
+	for _, entry := range playlist.Entries {
+		// Private and deleted videos are listed without a duration
+		if entry.ID == "" || entry.Duration == nil {
+			continue
+		}
+
+		channel := entry.Channel
+		if channel == "" {
+			channel = entry.Uploader
+		}
+
+		videos = append(videos, VideoInfo{
+			ID:       entry.ID,
+			Title:    entry.Title,
+			Duration: SecsToMins(int(*entry.Duration)),
+			Channel:  channel,
+			Link:     fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.ID),
+		})
+
+		if len(videos) >= limit {
+			break
+		}
+	}
+
+	if len(videos) == 0 {
+		return nil, fmt.Errorf("no playable tracks in playlist")
+	}
+
+	return videos, nil
+}
+
 // Global YouTube handler instance
 var YTube = NewYouTubeHandler()
 